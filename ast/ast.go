@@ -38,12 +38,33 @@ type Program struct {
 	Statements []Statement
 }
 
+// Scope tells where the value of an identifier is found, as computed by the
+// resolver.
+type Scope int
+
+const (
+	UNRESOLVED Scope = iota // looked up by name at run time
+	LOCAL                   // Slot of the function scope Depth levels out
+	GLOBAL                  // the top-level environment
+)
+
 type LetDeclaration struct {
 	Token token.Token // The token.LET token
 	Name  *Identifier
 	Value Expression
 }
 
+type ImportStatement struct {
+	Token token.Token // The token.IMPORT token
+	Path  *StringLiteral
+	Name  *Identifier
+}
+
+type ExportDeclaration struct {
+	Token       token.Token // The token.EXPORT token
+	Declaration *LetDeclaration
+}
+
 type ReturnStatement struct {
 	Token       token.Token // The token.RETURN token
 	ReturnValue Expression
@@ -57,11 +78,15 @@ type ExpressionStatement struct {
 type Block struct {
 	Token      token.Token // The token.LBRACE token
 	Statements []Statement
+	Closing    token.Token // The token.RBRACE token
 }
 
 type Identifier struct {
 	Token token.Token // The token.IDENT token
 	Value string
+	Scope Scope
+	Depth int
+	Slot  int
 }
 
 type NumberLiteral struct {
@@ -85,6 +110,7 @@ type InfixExpression struct {
 type GroupedExpression struct {
 	Token      token.Token // The token.LPAREN token
 	Expression Expression
+	Closing    token.Token // The token.RPAREN token
 }
 
 type Boolean struct {
@@ -92,6 +118,10 @@ type Boolean struct {
 	Value bool
 }
 
+type Null struct {
+	Token token.Token // The token.NULL token
+}
+
 type IfExpression struct {
 	Token       token.Token // The token.IF token
 	Condition   Expression
@@ -103,12 +133,14 @@ type FunctionLiteral struct {
 	Token      token.Token // The token.FN token
 	Parameters []*Identifier
 	Body       *Block
+	Locals     []string // names of the slots of the function scope, if resolved
 }
 
 type CallExpression struct {
 	Token    token.Token // The token.LPAREN token
 	Function Expression  // Identifier or FunctionLiteral
 	Argument []Expression
+	Closing  token.Token // The token.RPAREN token
 }
 
 type StringLiteral struct {
@@ -119,17 +151,20 @@ type StringLiteral struct {
 type ArrayLiteral struct {
 	Token    token.Token // The token.LBRACKET token
 	Elements []Expression
+	Closing  token.Token // The token.RBRACKET token
 }
 
 type IndexExpression struct {
-	Token token.Token // The token.LBRACKET token
-	Left  Expression
-	Index Expression
+	Token   token.Token // The token.LBRACKET token
+	Left    Expression
+	Index   Expression
+	Closing token.Token // The token.RBRACKET token
 }
 
 type HashLiteral struct {
-	Token token.Token // The token.LBRACE token
-	Pairs map[Expression]Expression
+	Token   token.Token // The token.LBRACE token
+	Pairs   map[Expression]Expression
+	Closing token.Token // The token.RBRACE token
 }
 
 type MacroLiteral struct {
@@ -163,11 +198,19 @@ func (p *Program) String() string {
  *                               DECLARATION                                 *
  *****************************************************************************/
 
-func (ld *LetDeclaration) statementNode() {}
+func (ld *LetDeclaration) statementNode()    {}
+func (is *ImportStatement) statementNode()   {}
+func (ed *ExportDeclaration) statementNode() {}
 
 func (ld *LetDeclaration) TokenLexeme() string {
 	return ld.Token.Lexeme
 }
+func (is *ImportStatement) TokenLexeme() string {
+	return is.Token.Lexeme
+}
+func (ed *ExportDeclaration) TokenLexeme() string {
+	return ed.Token.Lexeme
+}
 
 func (ld *LetDeclaration) String() string {
 	var out bytes.Buffer
@@ -184,6 +227,20 @@ func (ld *LetDeclaration) String() string {
 
 	return out.String()
 }
+func (is *ImportStatement) String() string {
+	var out bytes.Buffer
+
+	out.WriteString(is.TokenLexeme() + " ")
+	out.WriteString("\"" + is.Path.Value + "\"")
+	out.WriteString(" as ")
+	out.WriteString(is.Name.String())
+	out.WriteString(";")
+
+	return out.String()
+}
+func (ed *ExportDeclaration) String() string {
+	return ed.TokenLexeme() + " " + ed.Declaration.String()
+}
 
 /*****************************************************************************
  *                                STATEMENTS                                 *
@@ -243,6 +300,7 @@ func (pe *PrefixExpression) expressionNode()  {}
 func (ie *InfixExpression) expressionNode()   {}
 func (ge *GroupedExpression) expressionNode() {}
 func (b *Boolean) expressionNode()            {}
+func (n *Null) expressionNode()               {}
 func (ie *IfExpression) expressionNode()      {}
 func (fl *FunctionLiteral) expressionNode()   {}
 func (ce *CallExpression) expressionNode()    {}
@@ -270,6 +328,9 @@ func (ge *GroupedExpression) TokenLexeme() string {
 func (b *Boolean) TokenLexeme() string {
 	return b.Token.Lexeme
 }
+func (n *Null) TokenLexeme() string {
+	return n.Token.Lexeme
+}
 func (ie *IfExpression) TokenLexeme() string {
 	return ie.Token.Lexeme
 }
@@ -328,6 +389,9 @@ func (ge *GroupedExpression) String() string {
 func (b *Boolean) String() string {
 	return b.Token.Lexeme
 }
+func (n *Null) String() string {
+	return n.Token.Lexeme
+}
 func (ie *IfExpression) String() string {
 	var out bytes.Buffer
 
@@ -398,8 +462,8 @@ func (hl *HashLiteral) String() string {
 	var out bytes.Buffer
 
 	var pairs []string
-	for key, value := range hl.Pairs {
-		pairs = append(pairs, key.String()+":"+value.String())
+	for _, key := range sortedKeys(hl) {
+		pairs = append(pairs, key.String()+":"+hl.Pairs[key].String())
 	}
 
 	out.WriteString("{" + strings.Join(pairs, ", ") + "}")
@@ -0,0 +1,159 @@
+package ast
+
+/*****************************************************************************
+ *                              PUBLIC FUNCTIONS                             *
+ *****************************************************************************/
+
+// Clone returns a deep copy of node, which shares no node with it.
+func Clone(node Node) Node {
+	if node == nil {
+		return nil
+	}
+	// an identity rewrite copies every node and cannot fail
+	clone, _ := Rewrite(node, func(node Node) Node { return node })
+	return clone
+}
+
+// Equal reports whether a and b are the same tree. Positions, lexemes and the
+// addresses given by the resolver are ignored, so `1` equals `1.0` wherever
+// they appear.
+func Equal(a, b Node) bool {
+	aNil, bNil := a == nil || isNilNode(a), b == nil || isNilNode(b)
+	if aNil || bNil {
+		return aNil && bNil
+	}
+
+	switch a := a.(type) {
+	case *Program:
+		b, ok := b.(*Program)
+		return ok && equalStatements(a.Statements, b.Statements)
+	case *LetDeclaration:
+		b, ok := b.(*LetDeclaration)
+		return ok && Equal(a.Name, b.Name) && Equal(a.Value, b.Value)
+	case *ImportStatement:
+		b, ok := b.(*ImportStatement)
+		return ok && Equal(a.Path, b.Path) && Equal(a.Name, b.Name)
+	case *ExportDeclaration:
+		b, ok := b.(*ExportDeclaration)
+		return ok && Equal(a.Declaration, b.Declaration)
+	case *ReturnStatement:
+		b, ok := b.(*ReturnStatement)
+		return ok && Equal(a.ReturnValue, b.ReturnValue)
+	case *ExpressionStatement:
+		b, ok := b.(*ExpressionStatement)
+		return ok && Equal(a.Expression, b.Expression)
+	case *Block:
+		b, ok := b.(*Block)
+		return ok && equalStatements(a.Statements, b.Statements)
+	case *Identifier:
+		b, ok := b.(*Identifier)
+		return ok && a.Value == b.Value
+	case *NumberLiteral:
+		b, ok := b.(*NumberLiteral)
+		return ok && a.Value == b.Value
+	case *StringLiteral:
+		b, ok := b.(*StringLiteral)
+		return ok && a.Value == b.Value
+	case *Boolean:
+		b, ok := b.(*Boolean)
+		return ok && a.Value == b.Value
+	case *Null:
+		_, ok := b.(*Null)
+		return ok
+	case *PrefixExpression:
+		b, ok := b.(*PrefixExpression)
+		return ok && a.Operator == b.Operator && Equal(a.Right, b.Right)
+	case *InfixExpression:
+		b, ok := b.(*InfixExpression)
+		return ok && a.Operator == b.Operator && Equal(a.Left, b.Left) && Equal(a.Right, b.Right)
+	case *GroupedExpression:
+		b, ok := b.(*GroupedExpression)
+		return ok && Equal(a.Expression, b.Expression)
+	case *IfExpression:
+		b, ok := b.(*IfExpression)
+		return ok && Equal(a.Condition, b.Condition) && Equal(a.Consequence, b.Consequence) && Equal(a.Alternative, b.Alternative)
+	case *FunctionLiteral:
+		b, ok := b.(*FunctionLiteral)
+		return ok && equalIdentifiers(a.Parameters, b.Parameters) && Equal(a.Body, b.Body)
+	case *MacroLiteral:
+		b, ok := b.(*MacroLiteral)
+		return ok && equalIdentifiers(a.Parameters, b.Parameters) && Equal(a.Body, b.Body)
+	case *CallExpression:
+		b, ok := b.(*CallExpression)
+		return ok && Equal(a.Function, b.Function) && equalExpressions(a.Argument, b.Argument)
+	case *ArrayLiteral:
+		b, ok := b.(*ArrayLiteral)
+		return ok && equalExpressions(a.Elements, b.Elements)
+	case *IndexExpression:
+		b, ok := b.(*IndexExpression)
+		return ok && Equal(a.Left, b.Left) && Equal(a.Index, b.Index)
+	case *HashLiteral:
+		b, ok := b.(*HashLiteral)
+		return ok && equalPairs(a.Pairs, b.Pairs)
+	default:
+		return a == b
+	}
+}
+
+/*****************************************************************************
+ *                             PRIVATE FUNCTIONS                             *
+ *****************************************************************************/
+
+func equalStatements(a, b []Statement) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !Equal(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func equalExpressions(a, b []Expression) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !Equal(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func equalIdentifiers(a, b []*Identifier) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !Equal(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// equalPairs reports whether every pair of a has its own equal pair in b. The
+// keys are compared as trees, not by the pointers of the maps.
+func equalPairs(a, b map[Expression]Expression) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	matched := make(map[Expression]bool, len(b))
+	for key, val := range a {
+		found := false
+		for other, otherVal := range b {
+			if !matched[other] && Equal(key, other) && Equal(val, otherVal) {
+				matched[other] = true
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
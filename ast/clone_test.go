@@ -0,0 +1,73 @@
+package ast
+
+import (
+	"github.com/digital-codex/assertions"
+	"github.com/digital-codex/monkey/token"
+	"strconv"
+	"testing"
+)
+
+func TestClone(t *testing.T) {
+	original := sample()
+	clone := Clone(original)
+	assertions.AssertStringEquals(t, original.String(), clone.String(), "clone.String() wrong")
+	assertions.AssertBoolEquals(t, true, Equal(original, clone), "Equal(original, clone) wrong")
+
+	shared := make(map[Node]bool)
+	Inspect(original, func(node Node) bool {
+		shared[node] = true
+		return true
+	})
+	Inspect(clone, func(node Node) bool {
+		assertions.AssertBoolEquals(t, false, shared[node], "clone shares a "+label(node)+" node")
+		return true
+	})
+
+	Modify(clone, func(node Node) Node {
+		if ident, ok := node.(*Identifier); ok {
+			ident.Value = "y"
+		}
+		return node
+	})
+	assertions.AssertStringEquals(t, sample().String(), original.String(), "original changed")
+
+	assertions.AssertEquals(t, nil, Clone(nil), "Clone(nil) wrong")
+}
+
+func TestEqual(t *testing.T) {
+	at := func(line, start int) token.Token {
+		return token.Token{Type: token.IDENT, Lexeme: "x", Line: line, Start: start}
+	}
+	hash := func(keys ...string) *HashLiteral {
+		pairs := make(map[Expression]Expression)
+		for i, key := range keys {
+			pairs[str(key)] = number(float64(i))
+		}
+		return &HashLiteral{Pairs: pairs}
+	}
+
+	tests := []struct {
+		a, b     Node
+		expected bool
+	}{
+		{sample(), sample(), true},
+		{&Identifier{Token: at(1, 0), Value: "x"}, &Identifier{Token: at(3, 17), Value: "x", Scope: LOCAL, Slot: 2}, true},
+		{&NumberLiteral{Token: token.Token{Lexeme: "1"}, Value: 1}, &NumberLiteral{Token: token.Token{Lexeme: "1.0"}, Value: 1}, true},
+		{&Identifier{Value: "x"}, &Identifier{Value: "y"}, false},
+		{&Identifier{Value: "x"}, str("x"), false},
+		{&InfixExpression{Left: number(1), Operator: "+", Right: number(2)}, &InfixExpression{Left: number(1), Operator: "-", Right: number(2)}, false},
+		{&IfExpression{Condition: &Boolean{Value: true}, Consequence: &Block{}}, &IfExpression{Condition: &Boolean{Value: true}, Consequence: &Block{}, Alternative: &Block{}}, false},
+		{&CallExpression{Function: &Identifier{Value: "f"}}, &CallExpression{Function: &Identifier{Value: "f"}, Argument: []Expression{}}, true},
+		{&CallExpression{Function: &Identifier{Value: "f"}, Argument: []Expression{number(1)}}, &CallExpression{Function: &Identifier{Value: "f"}}, false},
+		{hash("a", "b"), hash("a", "b"), true},
+		{hash("a", "b"), hash("b", "a"), false},
+		{&Null{}, &Null{}, true},
+		{nil, (*Block)(nil), true},
+		{nil, &Null{}, false},
+	}
+
+	for i, test := range tests {
+		assertions.AssertBoolEquals(t, test.expected, Equal(test.a, test.b), "test["+strconv.Itoa(i)+"] - Equal(a, b) wrong")
+		assertions.AssertBoolEquals(t, test.expected, Equal(test.b, test.a), "test["+strconv.Itoa(i)+"] - Equal(b, a) wrong")
+	}
+}
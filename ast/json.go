@@ -0,0 +1,455 @@
+package ast
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/digital-codex/monkey/token"
+)
+
+/*****************************************************************************
+ *                                  TYPES                                    *
+ *****************************************************************************/
+
+// jsonNode is a node as ToJSON writes it. Every node has a kind, named as by
+// Kind, its token, e.g. the operator of an infix expression, and the span of
+// source it covers, from the start of its first token to the end of its last
+// as given by First and Last. Nodes ending in a bracket also have the closing
+// token. The other fields are set by kind:
+//
+//	program, block  statements
+//	let             name, value
+//	import          path, name
+//	export          declaration
+//	return          value
+//	expression      expression
+//	identifier      literal (the name)
+//	number, string  literal
+//	boolean         literal
+//	prefix          operator, right
+//	infix           left, operator, right
+//	grouped         expression
+//	if              condition, consequence, alternative (when there is one)
+//	function, macro parameters, body
+//	call            function, arguments
+//	array           elements
+//	index           left, index
+//	hash            pairs, each a key and a value, ordered by the source of
+//	                the keys
+//
+// Empty lists are left out. The addresses given by the resolver are not part
+// of the tree and are not written.
+type jsonNode struct {
+	Kind        string      `json:"kind"`
+	Token       jsonToken   `json:"token"`
+	Closing     *jsonToken  `json:"closing,omitempty"`
+	Start       jsonPlace   `json:"start"`
+	End         jsonPlace   `json:"end"`
+	Literal     any         `json:"literal,omitempty"`
+	Operator    string      `json:"operator,omitempty"`
+	Name        *jsonNode   `json:"name,omitempty"`
+	Path        *jsonNode   `json:"path,omitempty"`
+	Declaration *jsonNode   `json:"declaration,omitempty"`
+	Value       *jsonNode   `json:"value,omitempty"`
+	Expression  *jsonNode   `json:"expression,omitempty"`
+	Left        *jsonNode   `json:"left,omitempty"`
+	Right       *jsonNode   `json:"right,omitempty"`
+	Index       *jsonNode   `json:"index,omitempty"`
+	Condition   *jsonNode   `json:"condition,omitempty"`
+	Consequence *jsonNode   `json:"consequence,omitempty"`
+	Alternative *jsonNode   `json:"alternative,omitempty"`
+	Function    *jsonNode   `json:"function,omitempty"`
+	Body        *jsonNode   `json:"body,omitempty"`
+	Statements  []*jsonNode `json:"statements,omitempty"`
+	Parameters  []*jsonNode `json:"parameters,omitempty"`
+	Arguments   []*jsonNode `json:"arguments,omitempty"`
+	Elements    []*jsonNode `json:"elements,omitempty"`
+	Pairs       []jsonPair  `json:"pairs,omitempty"`
+}
+
+type jsonToken struct {
+	Type   string `json:"type"`
+	Lexeme string `json:"lexeme"`
+	Line   int    `json:"line"`
+	Start  int    `json:"start"`
+	Length int    `json:"length"`
+}
+
+// jsonPlace is a place in the source: a line and the offset from the start of
+// the source.
+type jsonPlace struct {
+	Line   int `json:"line"`
+	Offset int `json:"offset"`
+}
+
+type jsonPair struct {
+	Key   *jsonNode `json:"key"`
+	Value *jsonNode `json:"value"`
+}
+
+// decoder holds the first error of FromJSON, after which the rest of the
+// tree is left out.
+type decoder struct {
+	err error
+}
+
+/*****************************************************************************
+ *                              PUBLIC FUNCTIONS                             *
+ *****************************************************************************/
+
+// ToJSON encodes the tree of node in the JSON schema described by jsonNode.
+func ToJSON(node Node) ([]byte, error) {
+	encoded, err := encode(node)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(encoded)
+}
+
+// FromJSON decodes a tree written by ToJSON. It fails when a node is of an
+// unknown kind or stands where its kind cannot, e.g. a statement among the
+// arguments of a call.
+func FromJSON(data []byte) (Node, error) {
+	var root jsonNode
+	if err := json.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("ast: %w", err)
+	}
+
+	d := &decoder{}
+	node := d.node(&root)
+	if d.err != nil {
+		return nil, d.err
+	}
+	return node, nil
+}
+
+/*****************************************************************************
+ *                             PRIVATE FUNCTIONS                             *
+ *****************************************************************************/
+
+func encode(node Node) (*jsonNode, error) {
+	if node == nil || isNilNode(node) {
+		return nil, nil
+	}
+
+	var err error
+	child := func(node Node) *jsonNode {
+		if err != nil {
+			return nil
+		}
+		var encoded *jsonNode
+		encoded, err = encode(node)
+		return encoded
+	}
+	statements := func(stmts []Statement) []*jsonNode {
+		var encoded []*jsonNode
+		for _, stmt := range stmts {
+			encoded = append(encoded, child(stmt))
+		}
+		return encoded
+	}
+	expressions := func(exps []Expression) []*jsonNode {
+		var encoded []*jsonNode
+		for _, exp := range exps {
+			encoded = append(encoded, child(exp))
+		}
+		return encoded
+	}
+	identifiers := func(idents []*Identifier) []*jsonNode {
+		var encoded []*jsonNode
+		for _, ident := range idents {
+			encoded = append(encoded, child(ident))
+		}
+		return encoded
+	}
+
+	n := &jsonNode{Kind: Kind(node)}
+	switch node := node.(type) {
+	case *Program:
+		n.Token = encodeToken(First(node))
+		n.Statements = statements(node.Statements)
+	case *LetDeclaration:
+		n.Token = encodeToken(node.Token)
+		n.Name = child(node.Name)
+		n.Value = child(node.Value)
+	case *ImportStatement:
+		n.Token = encodeToken(node.Token)
+		n.Path = child(node.Path)
+		n.Name = child(node.Name)
+	case *ExportDeclaration:
+		n.Token = encodeToken(node.Token)
+		n.Declaration = child(node.Declaration)
+	case *ReturnStatement:
+		n.Token = encodeToken(node.Token)
+		n.Value = child(node.ReturnValue)
+	case *ExpressionStatement:
+		n.Token = encodeToken(node.Token)
+		n.Expression = child(node.Expression)
+	case *Block:
+		n.Token = encodeToken(node.Token)
+		n.Closing = encodeClosing(node.Closing)
+		n.Statements = statements(node.Statements)
+	case *Identifier:
+		n.Token = encodeToken(node.Token)
+		n.Literal = node.Value
+	case *NumberLiteral:
+		n.Token = encodeToken(node.Token)
+		n.Literal = node.Value
+	case *StringLiteral:
+		n.Token = encodeToken(node.Token)
+		n.Literal = node.Value
+	case *Boolean:
+		n.Token = encodeToken(node.Token)
+		n.Literal = node.Value
+	case *Null:
+		n.Token = encodeToken(node.Token)
+	case *PrefixExpression:
+		n.Token = encodeToken(node.Token)
+		n.Operator = node.Operator
+		n.Right = child(node.Right)
+	case *InfixExpression:
+		n.Token = encodeToken(node.Token)
+		n.Left = child(node.Left)
+		n.Operator = node.Operator
+		n.Right = child(node.Right)
+	case *GroupedExpression:
+		n.Token = encodeToken(node.Token)
+		n.Closing = encodeClosing(node.Closing)
+		n.Expression = child(node.Expression)
+	case *IfExpression:
+		n.Token = encodeToken(node.Token)
+		n.Condition = child(node.Condition)
+		n.Consequence = child(node.Consequence)
+		n.Alternative = child(node.Alternative)
+	case *FunctionLiteral:
+		n.Token = encodeToken(node.Token)
+		n.Parameters = identifiers(node.Parameters)
+		n.Body = child(node.Body)
+	case *MacroLiteral:
+		n.Token = encodeToken(node.Token)
+		n.Parameters = identifiers(node.Parameters)
+		n.Body = child(node.Body)
+	case *CallExpression:
+		n.Token = encodeToken(node.Token)
+		n.Closing = encodeClosing(node.Closing)
+		n.Function = child(node.Function)
+		n.Arguments = expressions(node.Argument)
+	case *ArrayLiteral:
+		n.Token = encodeToken(node.Token)
+		n.Closing = encodeClosing(node.Closing)
+		n.Elements = expressions(node.Elements)
+	case *IndexExpression:
+		n.Token = encodeToken(node.Token)
+		n.Closing = encodeClosing(node.Closing)
+		n.Left = child(node.Left)
+		n.Index = child(node.Index)
+	case *HashLiteral:
+		n.Token = encodeToken(node.Token)
+		n.Closing = encodeClosing(node.Closing)
+		for _, key := range sortedKeys(node) {
+			n.Pairs = append(n.Pairs, jsonPair{Key: child(key), Value: child(node.Pairs[key])})
+		}
+	default:
+		return nil, fmt.Errorf("ast: cannot encode %T", node)
+	}
+
+	first, last := First(node), Last(node)
+	n.Start = jsonPlace{Line: first.Line, Offset: first.Start}
+	n.End = jsonPlace{Line: last.Line, Offset: last.Start + last.Length}
+
+	return n, err
+}
+
+func encodeToken(t token.Token) jsonToken {
+	return jsonToken{Type: t.Type.String(), Lexeme: t.Lexeme, Line: t.Line, Start: t.Start, Length: t.Length}
+}
+
+// encodeClosing encodes the closing bracket of a node, which is left out of a
+// tree that was not parsed.
+func encodeClosing(t token.Token) *jsonToken {
+	if t.Lexeme == "" {
+		return nil
+	}
+	encoded := encodeToken(t)
+	return &encoded
+}
+
+func (d *decoder) fail(format string, a ...any) {
+	if d.err == nil {
+		d.err = fmt.Errorf("ast: "+format, a...)
+	}
+}
+
+func (d *decoder) token(t jsonToken) token.Token {
+	for typ := token.ILLEGAL; typ <= token.EOF; typ++ {
+		if typ.String() == t.Type {
+			return token.Token{Type: typ, Lexeme: t.Lexeme, Line: t.Line, Start: t.Start, Length: t.Length}
+		}
+	}
+	d.fail("unknown token type %q", t.Type)
+	return token.Token{}
+}
+
+func (d *decoder) closing(n *jsonNode) token.Token {
+	if n.Closing == nil {
+		return token.Token{}
+	}
+	return d.token(*n.Closing)
+}
+
+func (d *decoder) node(n *jsonNode) Node {
+	if d.err != nil {
+		return nil
+	}
+
+	tok := d.token(n.Token)
+	switch n.Kind {
+	case "program":
+		return &Program{Statements: d.statements(n, n.Statements)}
+	case "let":
+		return &LetDeclaration{Token: tok, Name: d.identifier(n, n.Name), Value: d.expression(n, n.Value)}
+	case "import":
+		return &ImportStatement{Token: tok, Path: d.stringLiteral(n, n.Path), Name: d.identifier(n, n.Name)}
+	case "export":
+		return &ExportDeclaration{Token: tok, Declaration: d.letDeclaration(n, n.Declaration)}
+	case "return":
+		return &ReturnStatement{Token: tok, ReturnValue: d.expression(n, n.Value)}
+	case "expression":
+		return &ExpressionStatement{Token: tok, Expression: d.expression(n, n.Expression)}
+	case "block":
+		return &Block{Token: tok, Statements: d.statements(n, n.Statements), Closing: d.closing(n)}
+	case "identifier":
+		return &Identifier{Token: tok, Value: d.stringLiteralValue(n)}
+	case "number":
+		value, ok := n.Literal.(float64)
+		if !ok {
+			d.fail("number literal must be a number, got %T", n.Literal)
+		}
+		return &NumberLiteral{Token: tok, Value: value}
+	case "string":
+		return &StringLiteral{Token: tok, Value: d.stringLiteralValue(n)}
+	case "boolean":
+		value, ok := n.Literal.(bool)
+		if !ok {
+			d.fail("boolean literal must be a boolean, got %T", n.Literal)
+		}
+		return &Boolean{Token: tok, Value: value}
+	case "null":
+		return &Null{Token: tok}
+	case "prefix":
+		return &PrefixExpression{Token: tok, Operator: n.Operator, Right: d.expression(n, n.Right)}
+	case "infix":
+		return &InfixExpression{Token: tok, Left: d.expression(n, n.Left), Operator: n.Operator, Right: d.expression(n, n.Right)}
+	case "grouped":
+		return &GroupedExpression{Token: tok, Expression: d.expression(n, n.Expression), Closing: d.closing(n)}
+	case "if":
+		ie := &IfExpression{Token: tok, Condition: d.expression(n, n.Condition), Consequence: d.block(n, n.Consequence)}
+		if n.Alternative != nil {
+			ie.Alternative = d.block(n, n.Alternative)
+		}
+		return ie
+	case "function":
+		return &FunctionLiteral{Token: tok, Parameters: d.identifiers(n, n.Parameters), Body: d.block(n, n.Body)}
+	case "macro":
+		return &MacroLiteral{Token: tok, Parameters: d.identifiers(n, n.Parameters), Body: d.block(n, n.Body)}
+	case "call":
+		return &CallExpression{Token: tok, Function: d.expression(n, n.Function), Argument: d.expressions(n, n.Arguments), Closing: d.closing(n)}
+	case "array":
+		return &ArrayLiteral{Token: tok, Elements: d.expressions(n, n.Elements), Closing: d.closing(n)}
+	case "index":
+		return &IndexExpression{Token: tok, Left: d.expression(n, n.Left), Index: d.expression(n, n.Index), Closing: d.closing(n)}
+	case "hash":
+		pairs := make(map[Expression]Expression, len(n.Pairs))
+		for _, pair := range n.Pairs {
+			pairs[d.expression(n, pair.Key)] = d.expression(n, pair.Value)
+		}
+		return &HashLiteral{Token: tok, Pairs: pairs, Closing: d.closing(n)}
+	default:
+		d.fail("unknown node kind %q", n.Kind)
+		return nil
+	}
+}
+
+func (d *decoder) stringLiteralValue(n *jsonNode) string {
+	value, ok := n.Literal.(string)
+	if !ok {
+		d.fail("%s literal must be a string, got %T", n.Kind, n.Literal)
+	}
+	return value
+}
+
+func (d *decoder) statements(parent *jsonNode, nodes []*jsonNode) []Statement {
+	stmts := make([]Statement, 0, len(nodes))
+	for _, n := range nodes {
+		if stmt, ok := d.child(parent, n, "statement").(Statement); ok {
+			stmts = append(stmts, stmt)
+		}
+	}
+	return stmts
+}
+
+func (d *decoder) expressions(parent *jsonNode, nodes []*jsonNode) []Expression {
+	exps := make([]Expression, 0, len(nodes))
+	for _, n := range nodes {
+		if exp, ok := d.child(parent, n, "expression").(Expression); ok {
+			exps = append(exps, exp)
+		}
+	}
+	return exps
+}
+
+func (d *decoder) identifiers(parent *jsonNode, nodes []*jsonNode) []*Identifier {
+	idents := make([]*Identifier, 0, len(nodes))
+	for _, n := range nodes {
+		if ident, ok := d.child(parent, n, "identifier").(*Identifier); ok {
+			idents = append(idents, ident)
+		}
+	}
+	return idents
+}
+
+func (d *decoder) expression(parent *jsonNode, n *jsonNode) Expression {
+	exp, _ := d.child(parent, n, "expression").(Expression)
+	return exp
+}
+
+func (d *decoder) identifier(parent *jsonNode, n *jsonNode) *Identifier {
+	ident, _ := d.child(parent, n, "identifier").(*Identifier)
+	return ident
+}
+
+func (d *decoder) stringLiteral(parent *jsonNode, n *jsonNode) *StringLiteral {
+	str, _ := d.child(parent, n, "string").(*StringLiteral)
+	return str
+}
+
+func (d *decoder) letDeclaration(parent *jsonNode, n *jsonNode) *LetDeclaration {
+	let, _ := d.child(parent, n, "let declaration").(*LetDeclaration)
+	return let
+}
+
+func (d *decoder) block(parent *jsonNode, n *jsonNode) *Block {
+	block, _ := d.child(parent, n, "block").(*Block)
+	return block
+}
+
+// child decodes n, a child of parent, and checks that it is what parent
+// expects in its place.
+func (d *decoder) child(parent *jsonNode, n *jsonNode, expected string) Node {
+	if d.err != nil {
+		return nil
+	}
+	if n == nil {
+		d.fail("%s node is missing %s", parent.Kind, expected)
+		return nil
+	}
+
+	node := d.node(n)
+	if d.err != nil {
+		return nil
+	}
+	if !fits(node, expected) {
+		d.fail("%s node cannot hold %s node where %s is expected", parent.Kind, n.Kind, expected)
+		return nil
+	}
+	return node
+}
@@ -0,0 +1,129 @@
+package ast_test
+
+import (
+	"encoding/json"
+	"github.com/digital-codex/assertions"
+	"github.com/digital-codex/monkey/ast"
+	"github.com/digital-codex/monkey/parser"
+	"strconv"
+	"testing"
+)
+
+func TestJSONRoundTrip(t *testing.T) {
+	tests := []string{
+		`let x = 1; x`,
+		`import "lib.mk" as lib; export let y = lib["z"];`,
+		`let f = fn(a, b) { if (a < b) { return a; } else { b } }; f(1, 2.50)`,
+		`!true == (false != null)`,
+		`let m = macro(x) { quote(unquote(x) + 1) }; m(-2)`,
+		`[1, "two", [3]][0]; {"b": 2, "a": {}, 3: [4]}`,
+		`fn() {}()`,
+		``,
+	}
+
+	for i, input := range tests {
+		p := parser.New(input)
+		program := p.ParseProgram()
+		assertions.AssertIntEquals(t, 0, len(p.Errors()), "test["+strconv.Itoa(i)+"] - parser errors")
+
+		data, err := ast.ToJSON(program)
+		assertions.AssertEquals(t, nil, err, "test["+strconv.Itoa(i)+"] - ToJSON() err")
+		decoded, err := ast.FromJSON(data)
+		assertions.AssertEquals(t, nil, err, "test["+strconv.Itoa(i)+"] - FromJSON() err")
+		assertions.AssertBoolEquals(t, true, ast.Equal(program, decoded), "test["+strconv.Itoa(i)+"] - decoded tree wrong")
+		assertions.AssertStringEquals(t, program.String(), decoded.String(), "test["+strconv.Itoa(i)+"] - decoded.String() wrong")
+
+		again, err := ast.ToJSON(decoded)
+		assertions.AssertEquals(t, nil, err, "test["+strconv.Itoa(i)+"] - ToJSON() err")
+		assertions.AssertStringEquals(t, string(data), string(again), "test["+strconv.Itoa(i)+"] - encoding not stable")
+	}
+}
+
+func TestToJSON(t *testing.T) {
+	p := parser.New(`-x`)
+	data, err := ast.ToJSON(p.ParseProgram())
+	assertions.AssertEquals(t, nil, err, "ToJSON() err")
+
+	expected := `{"kind":"program","token":{"type":"-","lexeme":"-","line":1,"start":0,"length":1},` +
+		`"start":{"line":1,"offset":0},"end":{"line":1,"offset":2},"statements":[` +
+		`{"kind":"expression","token":{"type":"-","lexeme":"-","line":1,"start":0,"length":1},` +
+		`"start":{"line":1,"offset":0},"end":{"line":1,"offset":2},"expression":` +
+		`{"kind":"prefix","token":{"type":"-","lexeme":"-","line":1,"start":0,"length":1},` +
+		`"start":{"line":1,"offset":0},"end":{"line":1,"offset":2},"operator":"-","right":` +
+		`{"kind":"identifier","token":{"type":"IDENT","lexeme":"x","line":1,"start":1,"length":1},` +
+		`"start":{"line":1,"offset":1},"end":{"line":1,"offset":2},"literal":"x"}}}]}`
+	assertions.AssertStringEquals(t, expected, string(data), "ToJSON() wrong")
+}
+
+func TestToJSONSpans(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{`a + b`, []string{"program a + b", "expression a + b", "infix a + b", "identifier a", "identifier b"}},
+		{`f(x, "y" )`, []string{"program f(x, \"y\" )", "expression f(x, \"y\" )", "call f(x, \"y\" )", "identifier f", "identifier x", "string \"y\""}},
+		{`xs[0]`, []string{"program xs[0]", "expression xs[0]", "index xs[0]", "identifier xs", "number 0"}},
+		{`let h = {"k": (1)};`, []string{"program let h = {\"k\": (1)}", "let let h = {\"k\": (1)}", "identifier h", "hash {\"k\": (1)}", "string \"k\"", "grouped (1)", "number 1"}},
+		{"if (c) {\n    1\n} else {\n}", []string{
+			"program if (c) {\n    1\n} else {\n}",
+			"expression if (c) {\n    1\n} else {\n}",
+			"if if (c) {\n    1\n} else {\n}",
+			"identifier c",
+			"block {\n    1\n}",
+			"expression 1",
+			"number 1",
+			"block {\n}",
+		}},
+	}
+
+	for i, test := range tests {
+		data, err := ast.ToJSON(parser.New(test.input).ParseProgram())
+		assertions.AssertEquals(t, nil, err, "test["+strconv.Itoa(i)+"] - ToJSON() err")
+
+		var root any
+		_ = json.Unmarshal(data, &root)
+		var actual []string
+		var spans func(any)
+		spans = func(v any) {
+			switch v := v.(type) {
+			case map[string]any:
+				if kind, ok := v["kind"].(string); ok {
+					start := int(v["start"].(map[string]any)["offset"].(float64))
+					end := int(v["end"].(map[string]any)["offset"].(float64))
+					actual = append(actual, kind+" "+test.input[start:end])
+				}
+				for _, key := range []string{"statements", "name", "key", "value", "expression", "left", "right", "index", "condition", "consequence", "alternative", "function", "arguments", "pairs"} {
+					spans(v[key])
+				}
+			case []any:
+				for _, elem := range v {
+					spans(elem)
+				}
+			}
+		}
+		spans(root)
+		assertions.AssertDeepEquals(t, test.expected, actual, "test["+strconv.Itoa(i)+"] - spans wrong")
+	}
+}
+
+func TestFromJSONErrors(t *testing.T) {
+	ident := `{"kind":"identifier","token":{"type":"IDENT","lexeme":"x"},"literal":"x"}`
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`[]`, "ast: json: cannot unmarshal array into Go value of type ast.jsonNode"},
+		{`{"kind":"loop","token":{"type":"IDENT"}}`, `ast: unknown node kind "loop"`},
+		{`{"kind":"identifier","token":{"type":"WORD"},"literal":"x"}`, `ast: unknown token type "WORD"`},
+		{`{"kind":"number","token":{"type":"NUMBER"},"literal":"1"}`, "ast: number literal must be a number, got string"},
+		{`{"kind":"prefix","token":{"type":"-"},"operator":"-"}`, "ast: prefix node is missing expression"},
+		{`{"kind":"call","token":{"type":"("},"function":` + ident + `,"arguments":[{"kind":"block","token":{"type":"{"}}]}`, "ast: call node cannot hold block node where expression is expected"},
+		{`{"kind":"function","token":{"type":"fn"},"parameters":[{"kind":"number","token":{"type":"NUMBER"},"literal":1}],"body":{"kind":"block","token":{"type":"{"}}}`, "ast: function node cannot hold number node where identifier is expected"},
+	}
+
+	for i, test := range tests {
+		_, err := ast.FromJSON([]byte(test.input))
+		assertions.AssertNotNull(t, err, "test["+strconv.Itoa(i)+"] - err missing")
+		assertions.AssertStringEquals(t, test.expected, err.Error(), "test["+strconv.Itoa(i)+"] - err wrong")
+	}
+}
@@ -10,6 +10,9 @@ type Modifier func(Node) Node
  *                              PUBLIC FUNCTIONS                             *
  *****************************************************************************/
 
+// Modify replaces, in place and bottom-up, every node of the tree with what
+// modifier returns for it. It leaves macro literals and declared names alone;
+// Rewrite covers every node without touching the tree.
 func Modify(node Node, modifier Modifier) Node {
 	switch node := node.(type) {
 	case *Program:
@@ -18,6 +21,8 @@ func Modify(node Node, modifier Modifier) Node {
 		}
 	case *LetDeclaration:
 		node.Value = Modify(node.Value, modifier).(Expression)
+	case *ExportDeclaration:
+		node.Declaration = Modify(node.Declaration, modifier).(*LetDeclaration)
 	case *ReturnStatement:
 		node.ReturnValue = Modify(node.ReturnValue, modifier).(Expression)
 	case *ExpressionStatement:
@@ -234,6 +234,29 @@ func TestModify(t *testing.T) {
 			},
 			expected: &LetDeclaration{Value: &NumberLiteral{Value: 2}},
 		},
+		{
+			input: struct {
+				node     Node
+				modifier Modifier
+			}{
+				node: &ExportDeclaration{Declaration: &LetDeclaration{Value: &NumberLiteral{Value: 1}}},
+				modifier: func(node Node) Node {
+					integer, ok := node.(*NumberLiteral)
+					if !ok {
+						return node
+					}
+
+					if integer.Value != 1 {
+						return node
+					}
+
+					integer.Value = 2
+					return integer
+
+				},
+			},
+			expected: &ExportDeclaration{Declaration: &LetDeclaration{Value: &NumberLiteral{Value: 2}}},
+		},
 		{
 			input: struct {
 				node     Node
@@ -0,0 +1,297 @@
+package ast
+
+import "fmt"
+
+/*****************************************************************************
+ *                                  TYPES                                    *
+ *****************************************************************************/
+
+// rewriter holds the first error of a Rewrite, after which the rest of the
+// tree is copied as is.
+type rewriter struct {
+	f   func(Node) Node
+	err error
+}
+
+/*****************************************************************************
+ *                              PUBLIC FUNCTIONS                             *
+ *****************************************************************************/
+
+// Rewrite returns a copy of node in which every node has been replaced by what
+// f returns for it. Like Modify, f sees the children of a node, already
+// rewritten, before the node itself, but node is left untouched. Rewrite fails
+// when f returns a node that cannot take the place of the one it was given,
+// e.g. a statement where an expression is expected. Only an else block may be
+// dropped by returning nil.
+func Rewrite(node Node, f func(Node) Node) (Node, error) {
+	r := &rewriter{f: f}
+	rewritten := r.node(node)
+	if r.err != nil {
+		return nil, r.err
+	}
+	if rewritten == nil {
+		return nil, fmt.Errorf("ast: %T rewritten to nil", node)
+	}
+	return rewritten, nil
+}
+
+/*****************************************************************************
+ *                             PRIVATE FUNCTIONS                             *
+ *****************************************************************************/
+
+// node copies node and its children, then rewrites the copy.
+func (r *rewriter) node(node Node) Node {
+	switch node := node.(type) {
+	case *Program:
+		c := *node
+		c.Statements = r.statements(node, node.Statements)
+		return r.f(&c)
+	case *LetDeclaration:
+		c := *node
+		c.Name = r.identifier(node, node.Name)
+		c.Value = r.expression(node, node.Value)
+		return r.f(&c)
+	case *ImportStatement:
+		c := *node
+		c.Path = r.stringLiteral(node, node.Path)
+		c.Name = r.identifier(node, node.Name)
+		return r.f(&c)
+	case *ExportDeclaration:
+		c := *node
+		c.Declaration = r.letDeclaration(node, node.Declaration)
+		return r.f(&c)
+	case *ReturnStatement:
+		c := *node
+		c.ReturnValue = r.expression(node, node.ReturnValue)
+		return r.f(&c)
+	case *ExpressionStatement:
+		c := *node
+		c.Expression = r.expression(node, node.Expression)
+		return r.f(&c)
+	case *Block:
+		c := *node
+		c.Statements = r.statements(node, node.Statements)
+		return r.f(&c)
+	case *Identifier:
+		c := *node
+		return r.f(&c)
+	case *NumberLiteral:
+		c := *node
+		return r.f(&c)
+	case *StringLiteral:
+		c := *node
+		return r.f(&c)
+	case *Boolean:
+		c := *node
+		return r.f(&c)
+	case *Null:
+		c := *node
+		return r.f(&c)
+	case *PrefixExpression:
+		c := *node
+		c.Right = r.expression(node, node.Right)
+		return r.f(&c)
+	case *InfixExpression:
+		c := *node
+		c.Left = r.expression(node, node.Left)
+		c.Right = r.expression(node, node.Right)
+		return r.f(&c)
+	case *GroupedExpression:
+		c := *node
+		c.Expression = r.expression(node, node.Expression)
+		return r.f(&c)
+	case *IfExpression:
+		c := *node
+		c.Condition = r.expression(node, node.Condition)
+		c.Consequence = r.block(node, node.Consequence)
+		c.Alternative = r.alternative(node, node.Alternative)
+		return r.f(&c)
+	case *FunctionLiteral:
+		c := *node
+		c.Parameters = r.identifiers(node, node.Parameters)
+		c.Body = r.block(node, node.Body)
+		c.Locals = append([]string(nil), node.Locals...)
+		return r.f(&c)
+	case *MacroLiteral:
+		c := *node
+		c.Parameters = r.identifiers(node, node.Parameters)
+		c.Body = r.block(node, node.Body)
+		return r.f(&c)
+	case *CallExpression:
+		c := *node
+		c.Function = r.expression(node, node.Function)
+		c.Argument = r.expressions(node, node.Argument)
+		return r.f(&c)
+	case *ArrayLiteral:
+		c := *node
+		c.Elements = r.expressions(node, node.Elements)
+		return r.f(&c)
+	case *IndexExpression:
+		c := *node
+		c.Left = r.expression(node, node.Left)
+		c.Index = r.expression(node, node.Index)
+		return r.f(&c)
+	case *HashLiteral:
+		c := *node
+		c.Pairs = make(map[Expression]Expression, len(node.Pairs))
+		for _, key := range sortedKeys(node) {
+			c.Pairs[r.expression(node, key)] = r.expression(node, node.Pairs[key])
+		}
+		return r.f(&c)
+	default:
+		return r.f(node)
+	}
+}
+
+func (r *rewriter) statements(parent Node, stmts []Statement) []Statement {
+	if stmts == nil {
+		return nil
+	}
+	rewritten := make([]Statement, len(stmts))
+	for i, stmt := range stmts {
+		rewritten[i] = r.statement(parent, stmt)
+	}
+	return rewritten
+}
+
+func (r *rewriter) expressions(parent Node, exps []Expression) []Expression {
+	if exps == nil {
+		return nil
+	}
+	rewritten := make([]Expression, len(exps))
+	for i, exp := range exps {
+		rewritten[i] = r.expression(parent, exp)
+	}
+	return rewritten
+}
+
+func (r *rewriter) identifiers(parent Node, idents []*Identifier) []*Identifier {
+	if idents == nil {
+		return nil
+	}
+	rewritten := make([]*Identifier, len(idents))
+	for i, ident := range idents {
+		rewritten[i] = r.identifier(parent, ident)
+	}
+	return rewritten
+}
+
+func (r *rewriter) statement(parent Node, stmt Statement) Statement {
+	if stmt, ok := r.child(parent, stmt, "statement").(Statement); ok {
+		return stmt
+	}
+	return nil
+}
+
+func (r *rewriter) expression(parent Node, exp Expression) Expression {
+	if exp, ok := r.child(parent, exp, "expression").(Expression); ok {
+		return exp
+	}
+	return nil
+}
+
+func (r *rewriter) identifier(parent Node, ident *Identifier) *Identifier {
+	if ident, ok := r.child(parent, ident, "identifier").(*Identifier); ok {
+		return ident
+	}
+	return nil
+}
+
+func (r *rewriter) stringLiteral(parent Node, str *StringLiteral) *StringLiteral {
+	if str, ok := r.child(parent, str, "string").(*StringLiteral); ok {
+		return str
+	}
+	return nil
+}
+
+func (r *rewriter) letDeclaration(parent Node, let *LetDeclaration) *LetDeclaration {
+	if let, ok := r.child(parent, let, "let declaration").(*LetDeclaration); ok {
+		return let
+	}
+	return nil
+}
+
+func (r *rewriter) block(parent Node, block *Block) *Block {
+	if block, ok := r.child(parent, block, "block").(*Block); ok {
+		return block
+	}
+	return nil
+}
+
+// alternative rewrites the else block of parent, which unlike the other
+// children may be rewritten to nil.
+func (r *rewriter) alternative(parent Node, block *Block) *Block {
+	if r.err != nil || block == nil {
+		return block
+	}
+	switch rewritten := r.node(block).(type) {
+	case nil:
+		return nil
+	case *Block:
+		return rewritten
+	default:
+		r.err = fmt.Errorf("ast: %T cannot hold %T where block is expected", parent, rewritten)
+		return nil
+	}
+}
+
+// child rewrites node, a child of parent, and checks that the result is what
+// parent expects in its place.
+func (r *rewriter) child(parent Node, node Node, expected string) Node {
+	if r.err != nil || node == nil || isNilNode(node) {
+		return node
+	}
+
+	rewritten := r.node(node)
+	if rewritten == nil || isNilNode(rewritten) {
+		r.err = fmt.Errorf("ast: %T rewritten to nil where %s is expected in %T", node, expected, parent)
+		return nil
+	}
+	if !fits(rewritten, expected) {
+		r.err = fmt.Errorf("ast: %T cannot hold %T where %s is expected", parent, rewritten, expected)
+		return nil
+	}
+	return rewritten
+}
+
+// fits reports whether node may stand where the kind of node named by expected
+// is.
+func fits(node Node, expected string) bool {
+	switch expected {
+	case "statement":
+		_, ok := node.(Statement)
+		return ok
+	case "expression":
+		_, ok := node.(Expression)
+		return ok
+	case "identifier":
+		_, ok := node.(*Identifier)
+		return ok
+	case "string":
+		_, ok := node.(*StringLiteral)
+		return ok
+	case "let declaration":
+		_, ok := node.(*LetDeclaration)
+		return ok
+	case "block":
+		_, ok := node.(*Block)
+		return ok
+	}
+	return false
+}
+
+// isNilNode reports whether node is a typed nil pointer, such as the missing
+// name of a let that failed to parse.
+func isNilNode(node Node) bool {
+	switch node := node.(type) {
+	case *Identifier:
+		return node == nil
+	case *Block:
+		return node == nil
+	case *StringLiteral:
+		return node == nil
+	case *LetDeclaration:
+		return node == nil
+	}
+	return false
+}
@@ -0,0 +1,129 @@
+package ast
+
+import (
+	"github.com/digital-codex/assertions"
+	"strconv"
+	"testing"
+)
+
+func TestRewrite(t *testing.T) {
+	tests := []struct {
+		f        func(Node) Node
+		expected string
+	}{
+		{
+			f:        func(node Node) Node { return node },
+			expected: `let m = macro(x){if(x){f(x, 1)}else{{a:1}}};`,
+		},
+		{
+			f: func(node Node) Node {
+				if ident, ok := node.(*Identifier); ok && ident.Value == "x" {
+					return &Identifier{Value: "y"}
+				}
+				return node
+			},
+			expected: `let m = macro(y){if(y){f(y, 1)}else{{a:1}}};`,
+		},
+		{
+			f: func(node Node) Node {
+				if n, ok := node.(*NumberLiteral); ok {
+					return number(n.Value * 10)
+				}
+				return node
+			},
+			expected: `let m = macro(x){if(x){f(x, 10)}else{{a:10}}};`,
+		},
+		{
+			f: func(node Node) Node {
+				if ie, ok := node.(*IfExpression); ok {
+					return ie.Consequence
+				}
+				if block, ok := node.(*Block); ok && len(block.Statements) == 1 {
+					if _, ok := block.Statements[0].(*ExpressionStatement).Expression.(*HashLiteral); ok {
+						return nil
+					}
+				}
+				return node
+			},
+			expected: "",
+		},
+	}
+
+	for i, test := range tests {
+		original := sample()
+		before := original.String()
+		rewritten, err := Rewrite(original, test.f)
+		assertions.AssertStringEquals(t, before, original.String(), "test["+strconv.Itoa(i)+"] - original changed")
+		if test.expected == "" {
+			assertions.AssertNotNull(t, err, "test["+strconv.Itoa(i)+"] - err missing")
+			continue
+		}
+		assertions.AssertEquals(t, nil, err, "test["+strconv.Itoa(i)+"] - unexpected err")
+		assertions.AssertStringEquals(t, test.expected, rewritten.String(), "test["+strconv.Itoa(i)+"] - rewritten wrong")
+	}
+}
+
+func TestRewriteElse(t *testing.T) {
+	rewritten, err := Rewrite(sample(), func(node Node) Node {
+		if block, ok := node.(*Block); ok && len(block.Statements) == 1 {
+			if stmt, ok := block.Statements[0].(*ExpressionStatement); ok {
+				if _, ok := stmt.Expression.(*HashLiteral); ok {
+					return nil
+				}
+			}
+		}
+		return node
+	})
+	assertions.AssertEquals(t, nil, err, "unexpected err")
+	assertions.AssertStringEquals(t, `let m = macro(x){if(x){f(x, 1)}};`, rewritten.String(), "rewritten wrong")
+}
+
+func TestRewriteErrors(t *testing.T) {
+	tests := []struct {
+		f        func(Node) Node
+		expected string
+	}{
+		{
+			f: func(node Node) Node {
+				if ident, ok := node.(*Identifier); ok && ident.Value == "x" {
+					return number(1)
+				}
+				return node
+			},
+			expected: "ast: *ast.MacroLiteral cannot hold *ast.NumberLiteral where identifier is expected",
+		},
+		{
+			f: func(node Node) Node {
+				if call, ok := node.(*CallExpression); ok {
+					return &ExpressionStatement{Expression: call}
+				}
+				return node
+			},
+			expected: "ast: *ast.ExpressionStatement cannot hold *ast.ExpressionStatement where expression is expected",
+		},
+		{
+			f: func(node Node) Node {
+				if _, ok := node.(*LetDeclaration); ok {
+					return nil
+				}
+				return node
+			},
+			expected: "ast: *ast.LetDeclaration rewritten to nil where statement is expected in *ast.Program",
+		},
+		{
+			f: func(node Node) Node {
+				if _, ok := node.(*Program); ok {
+					return nil
+				}
+				return node
+			},
+			expected: "ast: *ast.Program rewritten to nil",
+		},
+	}
+
+	for i, test := range tests {
+		_, err := Rewrite(sample(), test.f)
+		assertions.AssertNotNull(t, err, "test["+strconv.Itoa(i)+"] - err missing")
+		assertions.AssertStringEquals(t, test.expected, err.Error(), "test["+strconv.Itoa(i)+"] - err wrong")
+	}
+}
@@ -0,0 +1,144 @@
+package ast
+
+import "github.com/digital-codex/monkey/token"
+
+/*****************************************************************************
+ *                              PUBLIC FUNCTIONS                             *
+ *****************************************************************************/
+
+// First returns the first token of node in the source.
+func First(node Node) token.Token {
+	switch node := node.(type) {
+	case *Program:
+		if len(node.Statements) > 0 {
+			return First(node.Statements[0])
+		}
+	case *ExpressionStatement:
+		if node.Expression != nil {
+			return First(node.Expression)
+		}
+	case *InfixExpression:
+		return First(node.Left)
+	case *CallExpression:
+		return First(node.Function)
+	case *IndexExpression:
+		return First(node.Left)
+	}
+	return tokenOf(node)
+}
+
+// Last returns the last token of node in the source, leaving out the
+// semicolon ending a statement. For a node ending in a bracket it is the
+// closing bracket, or the opening one in a tree that was not parsed.
+func Last(node Node) token.Token {
+	var last Node
+	switch node := node.(type) {
+	case *Program:
+		if len(node.Statements) > 0 {
+			last = node.Statements[len(node.Statements)-1]
+		}
+	case *LetDeclaration:
+		last = node.Value
+	case *ImportStatement:
+		last = node.Name
+	case *ExportDeclaration:
+		last = node.Declaration
+	case *ReturnStatement:
+		last = node.ReturnValue
+	case *ExpressionStatement:
+		last = node.Expression
+	case *PrefixExpression:
+		last = node.Right
+	case *InfixExpression:
+		last = node.Right
+	case *IfExpression:
+		last = node.Consequence
+		if node.Alternative != nil {
+			last = node.Alternative
+		}
+	case *FunctionLiteral:
+		last = node.Body
+	case *MacroLiteral:
+		last = node.Body
+	case *Block:
+		return closing(node.Token, node.Closing)
+	case *GroupedExpression:
+		return closing(node.Token, node.Closing)
+	case *CallExpression:
+		return closing(node.Token, node.Closing)
+	case *ArrayLiteral:
+		return closing(node.Token, node.Closing)
+	case *IndexExpression:
+		return closing(node.Token, node.Closing)
+	case *HashLiteral:
+		return closing(node.Token, node.Closing)
+	}
+
+	if last == nil || isNilNode(last) {
+		return tokenOf(node)
+	}
+	return Last(last)
+}
+
+/*****************************************************************************
+ *                             PRIVATE FUNCTIONS                             *
+ *****************************************************************************/
+
+// closing returns the closing bracket of a node, or the opening one when it
+// is not set.
+func closing(opening token.Token, closing token.Token) token.Token {
+	if closing.Lexeme == "" {
+		return opening
+	}
+	return closing
+}
+
+// tokenOf returns the token stored in node.
+func tokenOf(node Node) token.Token {
+	switch node := node.(type) {
+	case *LetDeclaration:
+		return node.Token
+	case *ImportStatement:
+		return node.Token
+	case *ExportDeclaration:
+		return node.Token
+	case *ReturnStatement:
+		return node.Token
+	case *ExpressionStatement:
+		return node.Token
+	case *Block:
+		return node.Token
+	case *Identifier:
+		return node.Token
+	case *NumberLiteral:
+		return node.Token
+	case *StringLiteral:
+		return node.Token
+	case *Boolean:
+		return node.Token
+	case *Null:
+		return node.Token
+	case *PrefixExpression:
+		return node.Token
+	case *InfixExpression:
+		return node.Token
+	case *GroupedExpression:
+		return node.Token
+	case *IfExpression:
+		return node.Token
+	case *FunctionLiteral:
+		return node.Token
+	case *MacroLiteral:
+		return node.Token
+	case *CallExpression:
+		return node.Token
+	case *ArrayLiteral:
+		return node.Token
+	case *IndexExpression:
+		return node.Token
+	case *HashLiteral:
+		return node.Token
+	default:
+		return token.Token{}
+	}
+}
@@ -0,0 +1,37 @@
+package ast_test
+
+import (
+	"github.com/digital-codex/assertions"
+	"github.com/digital-codex/monkey/ast"
+	"github.com/digital-codex/monkey/parser"
+	"strconv"
+	"testing"
+)
+
+func TestFirstLast(t *testing.T) {
+	tests := []struct {
+		input          string
+		first, last    string
+		firstAt, endAt int
+	}{
+		{`a + b * c;`, "a", "c", 0, 9},
+		{`f(x)(y)`, "f", ")", 0, 7},
+		{`m[0]["k"]`, "m", "]", 0, 9},
+		{`let f = fn(x) { x };`, "let", "}", 0, 19},
+		{`return -(1);`, "return", ")", 0, 11},
+		{`if (x) { 1 } else { 2 }`, "if", "}", 0, 23},
+		{`import "lib.mk" as lib;`, "import", "lib", 0, 22},
+	}
+
+	for i, test := range tests {
+		program := parser.New(test.input).ParseProgram()
+		first, last := ast.First(program), ast.Last(program)
+		assertions.AssertStringEquals(t, test.first, first.Lexeme, "test["+strconv.Itoa(i)+"] - First() wrong")
+		assertions.AssertStringEquals(t, test.last, last.Lexeme, "test["+strconv.Itoa(i)+"] - Last() wrong")
+		assertions.AssertIntEquals(t, test.firstAt, first.Start, "test["+strconv.Itoa(i)+"] - First().Start wrong")
+		assertions.AssertIntEquals(t, test.endAt, last.Start+last.Length, "test["+strconv.Itoa(i)+"] - end of Last() wrong")
+	}
+
+	hash := &ast.HashLiteral{Token: ast.First(parser.New(`{}`).ParseProgram())}
+	assertions.AssertStringEquals(t, "{", ast.Last(hash).Lexeme, "Last() of a hash that was not parsed wrong")
+}
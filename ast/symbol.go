@@ -0,0 +1,78 @@
+package ast
+
+import "strings"
+
+/*****************************************************************************
+ *                                  TYPES                                    *
+ *****************************************************************************/
+
+// SYMBOL_SEPARATOR joins the prefix and the number in the name of a symbol, an
+// identifier made by gensym or by a hygienic macro. No identifier of the
+// source code can hold it, so a symbol never clashes with a name the user
+// wrote.
+const SYMBOL_SEPARATOR = "#"
+
+/*****************************************************************************
+ *                              PUBLIC FUNCTIONS                             *
+ *****************************************************************************/
+
+// RenameSymbols returns a copy of node in which each symbol is renamed to its
+// prefix and number joined by "_", with more "_" appended while the name is
+// taken by another identifier of node. Unlike node, the copy prints as source
+// code that parses back to the same program.
+func RenameSymbols(node Node) Node {
+	clone := Clone(node)
+
+	taken := make(map[string]bool)
+	Inspect(clone, func(node Node) bool {
+		if ident, ok := node.(*Identifier); ok {
+			taken[ident.Value] = true
+		}
+		return true
+	})
+
+	renamed := make(map[string]string)
+	Inspect(clone, func(node Node) bool {
+		ident, ok := node.(*Identifier)
+		if !ok || !strings.Contains(ident.Value, SYMBOL_SEPARATOR) {
+			return true
+		}
+
+		name, ok := renamed[ident.Value]
+		if !ok {
+			prefix, number, _ := strings.Cut(ident.Value, SYMBOL_SEPARATOR)
+			if !isIdentifier(prefix) {
+				// gensym takes any string as prefix
+				prefix = "g"
+			}
+			name = prefix + "_" + number
+			for taken[name] {
+				name += "_"
+			}
+			taken[name] = true
+			renamed[ident.Value] = name
+		}
+		ident.Value = name
+		ident.Token.Lexeme = name
+		return true
+	})
+
+	return clone
+}
+
+/*****************************************************************************
+ *                             PRIVATE FUNCTIONS                             *
+ *****************************************************************************/
+
+// isIdentifier reports whether name is spelled like an identifier of the
+// source code.
+func isIdentifier(name string) bool {
+	for i := 0; i < len(name); i++ {
+		ch := name[i]
+		alpha := ch == '_' || ('a' <= ch && ch <= 'z') || ('A' <= ch && ch <= 'Z')
+		if !alpha && (i == 0 || ch < '0' || ch > '9') {
+			return false
+		}
+	}
+	return name != ""
+}
@@ -0,0 +1,43 @@
+package ast_test
+
+import (
+	"github.com/digital-codex/assertions"
+	"github.com/digital-codex/monkey/ast"
+	"github.com/digital-codex/monkey/parser"
+	"strconv"
+	"testing"
+)
+
+func TestRenameSymbols(t *testing.T) {
+	tests := []struct {
+		input    string
+		name     string
+		symbol   string
+		expected string
+	}{
+		{`fn(a) { a + 1 }`, "a", "t#1", `fn(t_1){(t_1 + 1)}`},
+		{`fn(a) { a + t_1 }`, "a", "t#1", `fn(t_1_){(t_1_ + t_1)}`},
+		{`let a = 1; a`, "a", "x-y#2", `let g_2 = 1;g_2`},
+		{`quote(a)`, "b", "t#1", `quote(a)`},
+	}
+
+	for i, test := range tests {
+		program := parser.New(test.input).ParseProgram()
+		ast.Inspect(program, func(node ast.Node) bool {
+			if ident, ok := node.(*ast.Identifier); ok && ident.Value == test.name {
+				ident.Value = test.symbol
+			}
+			return true
+		})
+		original := program.String()
+
+		renamed := ast.RenameSymbols(program)
+		assertions.AssertStringEquals(t, test.expected, renamed.String(), "test["+strconv.Itoa(i)+"] - renamed.String() wrong")
+		assertions.AssertStringEquals(t, original, program.String(), "test["+strconv.Itoa(i)+"] - program changed")
+
+		p := parser.New(renamed.String())
+		reparsed := p.ParseProgram()
+		assertions.AssertIntEquals(t, 0, len(p.Errors()), "test["+strconv.Itoa(i)+"] - renamed program does not parse")
+		assertions.AssertStringEquals(t, test.expected, reparsed.String(), "test["+strconv.Itoa(i)+"] - reparsed.String() wrong")
+	}
+}
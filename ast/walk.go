@@ -0,0 +1,214 @@
+package ast
+
+import "sort"
+
+/*****************************************************************************
+ *                                  TYPES                                    *
+ *****************************************************************************/
+
+// Action tells Walk how to go on after a visitor has seen a node.
+type Action int
+
+const (
+	CONTINUE Action = iota // walk the children of the node
+	SKIP                   // leave the children of the node out
+	STOP                   // end the walk
+)
+
+// Visitor is called by Walk on every node of a tree.
+type Visitor interface {
+	// Enter is called on a node before its children are walked.
+	Enter(node Node) Action
+	// Leave is called on a node after its children are walked. It is not
+	// called when Enter skipped them.
+	Leave(node Node) Action
+}
+
+// Hooks is a Visitor made of a pre-order and a post-order function, either of
+// which may be nil.
+type Hooks struct {
+	Pre  func(Node) Action
+	Post func(Node) Action
+}
+
+/*****************************************************************************
+ *                              PUBLIC FUNCTIONS                             *
+ *****************************************************************************/
+
+// Walk visits node and, depth first, every node below it in source order. It
+// reports whether the walk went to the end, that is no call returned STOP.
+func Walk(v Visitor, node Node) bool {
+	switch v.Enter(node) {
+	case STOP:
+		return false
+	case SKIP:
+		return true
+	}
+
+	for _, child := range Children(node) {
+		if !Walk(v, child) {
+			return false
+		}
+	}
+
+	return v.Leave(node) != STOP
+}
+
+// Inspect walks node like Walk, calling f on each node before its children,
+// which are skipped when f returns false.
+func Inspect(node Node, f func(Node) bool) {
+	Walk(Hooks{Pre: func(node Node) Action {
+		if f(node) {
+			return CONTINUE
+		}
+		return SKIP
+	}}, node)
+}
+
+// Children returns the nodes directly below node in source order, declared
+// names included. The pairs of a hash come as key then value, ordered by the
+// source of their keys.
+func Children(node Node) []Node {
+	var children []Node
+
+	switch node := node.(type) {
+	case *Program:
+		for _, stmt := range node.Statements {
+			children = append(children, stmt)
+		}
+	case *LetDeclaration:
+		children = append(children, node.Name, node.Value)
+	case *ImportStatement:
+		children = append(children, node.Path, node.Name)
+	case *ExportDeclaration:
+		children = append(children, node.Declaration)
+	case *ReturnStatement:
+		children = append(children, node.ReturnValue)
+	case *ExpressionStatement:
+		children = append(children, node.Expression)
+	case *Block:
+		for _, stmt := range node.Statements {
+			children = append(children, stmt)
+		}
+	case *PrefixExpression:
+		children = append(children, node.Right)
+	case *InfixExpression:
+		children = append(children, node.Left, node.Right)
+	case *GroupedExpression:
+		children = append(children, node.Expression)
+	case *IfExpression:
+		children = append(children, node.Condition, node.Consequence)
+		if node.Alternative != nil {
+			children = append(children, node.Alternative)
+		}
+	case *FunctionLiteral:
+		for _, param := range node.Parameters {
+			children = append(children, param)
+		}
+		children = append(children, node.Body)
+	case *MacroLiteral:
+		for _, param := range node.Parameters {
+			children = append(children, param)
+		}
+		children = append(children, node.Body)
+	case *CallExpression:
+		children = append(children, node.Function)
+		for _, arg := range node.Argument {
+			children = append(children, arg)
+		}
+	case *ArrayLiteral:
+		for _, elem := range node.Elements {
+			children = append(children, elem)
+		}
+	case *IndexExpression:
+		children = append(children, node.Left, node.Index)
+	case *HashLiteral:
+		for _, key := range sortedKeys(node) {
+			children = append(children, key, node.Pairs[key])
+		}
+	}
+
+	return children
+}
+
+// Kind returns the name of the type of node, e.g. "let" or "call", or
+// "unknown" for a type that is not part of the language.
+func Kind(node Node) string {
+	switch node.(type) {
+	case *Program:
+		return "program"
+	case *LetDeclaration:
+		return "let"
+	case *ImportStatement:
+		return "import"
+	case *ExportDeclaration:
+		return "export"
+	case *ReturnStatement:
+		return "return"
+	case *ExpressionStatement:
+		return "expression"
+	case *Block:
+		return "block"
+	case *Identifier:
+		return "identifier"
+	case *NumberLiteral:
+		return "number"
+	case *StringLiteral:
+		return "string"
+	case *Boolean:
+		return "boolean"
+	case *Null:
+		return "null"
+	case *PrefixExpression:
+		return "prefix"
+	case *InfixExpression:
+		return "infix"
+	case *GroupedExpression:
+		return "grouped"
+	case *IfExpression:
+		return "if"
+	case *FunctionLiteral:
+		return "function"
+	case *CallExpression:
+		return "call"
+	case *ArrayLiteral:
+		return "array"
+	case *IndexExpression:
+		return "index"
+	case *HashLiteral:
+		return "hash"
+	case *MacroLiteral:
+		return "macro"
+	default:
+		return "unknown"
+	}
+}
+
+func (h Hooks) Enter(node Node) Action {
+	if h.Pre == nil {
+		return CONTINUE
+	}
+	return h.Pre(node)
+}
+
+func (h Hooks) Leave(node Node) Action {
+	if h.Post == nil {
+		return CONTINUE
+	}
+	return h.Post(node)
+}
+
+/*****************************************************************************
+ *                             PRIVATE FUNCTIONS                             *
+ *****************************************************************************/
+
+func sortedKeys(node *HashLiteral) []Expression {
+	keys := make([]Expression, 0, len(node.Pairs))
+	for key := range node.Pairs {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].String() < keys[j].String()
+	})
+	return keys
+}
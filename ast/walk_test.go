@@ -0,0 +1,198 @@
+package ast
+
+import (
+	"github.com/digital-codex/assertions"
+	"github.com/digital-codex/monkey/token"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// number and str build literals with the lexeme String prints.
+func number(value float64) *NumberLiteral {
+	return &NumberLiteral{Token: token.Token{Type: token.NUMBER, Lexeme: strconv.FormatFloat(value, 'f', -1, 64)}, Value: value}
+}
+
+func str(value string) *StringLiteral {
+	return &StringLiteral{Token: token.Token{Type: token.STRING, Lexeme: value}, Value: value}
+}
+
+// sample is the tree of `let m = macro(x) { if (x) { f(x, 1) } else { {"a": 1} } };`.
+func sample() *Program {
+	x := func() *Identifier { return &Identifier{Value: "x"} }
+	return &Program{Statements: []Statement{
+		&LetDeclaration{
+			Token: token.Token{Type: token.LET, Lexeme: "let"},
+			Name:  &Identifier{Value: "m"},
+			Value: &MacroLiteral{
+				Token:      token.Token{Type: token.MACRO, Lexeme: "macro"},
+				Parameters: []*Identifier{x()},
+				Body: &Block{Statements: []Statement{
+					&ExpressionStatement{Expression: &IfExpression{
+						Token:     token.Token{Type: token.IF, Lexeme: "if"},
+						Condition: x(),
+						Consequence: &Block{Statements: []Statement{
+							&ExpressionStatement{Expression: &CallExpression{
+								Function: &Identifier{Value: "f"},
+								Argument: []Expression{x(), number(1)},
+							}},
+						}},
+						Alternative: &Block{Statements: []Statement{
+							&ExpressionStatement{Expression: &HashLiteral{Pairs: map[Expression]Expression{
+								str("a"): number(1),
+							}}},
+						}},
+					}},
+				}},
+			},
+		},
+	}}
+}
+
+// label names node in a trace of a walk.
+func label(node Node) string {
+	switch node := node.(type) {
+	case *Identifier:
+		return node.Value
+	case *StringLiteral:
+		return strconv.Quote(node.Value)
+	case *NumberLiteral:
+		return strconv.FormatFloat(node.Value, 'f', -1, 64)
+	default:
+		return typeName(node)
+	}
+}
+
+func typeName(node Node) string {
+	switch node.(type) {
+	case *Program:
+		return "program"
+	case *LetDeclaration:
+		return "let"
+	case *MacroLiteral:
+		return "macro"
+	case *Block:
+		return "block"
+	case *ExpressionStatement:
+		return "stmt"
+	case *IfExpression:
+		return "if"
+	case *CallExpression:
+		return "call"
+	case *HashLiteral:
+		return "hash"
+	default:
+		return "?"
+	}
+}
+
+func TestWalk(t *testing.T) {
+	tests := []struct {
+		pre      func(Node) Action
+		post     func(Node) Action
+		expected string
+		complete bool
+	}{
+		{
+			expected: "+program +let +m -m +macro +x -x +block +stmt +if +x -x +block +stmt +call +f -f +x -x +1 -1 -call -stmt -block +block +stmt +hash +\"a\" -\"a\" +1 -1 -hash -stmt -block -if -stmt -block -macro -let -program",
+			complete: true,
+		},
+		{
+			pre: func(node Node) Action {
+				if _, ok := node.(*IfExpression); ok {
+					return SKIP
+				}
+				return CONTINUE
+			},
+			expected: "+program +let +m -m +macro +x -x +block +stmt +if -stmt -block -macro -let -program",
+			complete: true,
+		},
+		{
+			pre: func(node Node) Action {
+				if _, ok := node.(*CallExpression); ok {
+					return STOP
+				}
+				return CONTINUE
+			},
+			expected: "+program +let +m -m +macro +x -x +block +stmt +if +x -x +block +stmt +call",
+			complete: false,
+		},
+		{
+			post: func(node Node) Action {
+				if _, ok := node.(*MacroLiteral); ok {
+					return STOP
+				}
+				return CONTINUE
+			},
+			expected: "+program +let +m -m +macro +x -x +block +stmt +if +x -x +block +stmt +call +f -f +x -x +1 -1 -call -stmt -block +block +stmt +hash +\"a\" -\"a\" +1 -1 -hash -stmt -block -if -stmt -block -macro",
+			complete: false,
+		},
+	}
+
+	for i, test := range tests {
+		var trace []string
+		hooks := Hooks{
+			Pre: func(node Node) Action {
+				trace = append(trace, "+"+label(node))
+				if test.pre != nil {
+					return test.pre(node)
+				}
+				return CONTINUE
+			},
+			Post: func(node Node) Action {
+				trace = append(trace, "-"+label(node))
+				if test.post != nil {
+					return test.post(node)
+				}
+				return CONTINUE
+			},
+		}
+		complete := Walk(hooks, sample())
+		assertions.AssertStringEquals(t, test.expected, strings.Join(trace, " "), "test["+strconv.Itoa(i)+"] - trace wrong")
+		assertions.AssertBoolEquals(t, test.complete, complete, "test["+strconv.Itoa(i)+"] - Walk() wrong")
+	}
+}
+
+func TestInspect(t *testing.T) {
+	var names []string
+	Inspect(sample(), func(node Node) bool {
+		if _, ok := node.(*HashLiteral); ok {
+			return false
+		}
+		if ident, ok := node.(*Identifier); ok {
+			names = append(names, ident.Value)
+		}
+		return true
+	})
+	assertions.AssertDeepEquals(t, []string{"m", "x", "x", "f", "x"}, names, "identifiers wrong")
+
+	count := 0
+	Inspect(sample(), func(node Node) bool {
+		count++
+		return true
+	})
+	assertions.AssertIntEquals(t, 20, count, "node count wrong")
+}
+
+func TestChildren(t *testing.T) {
+	tests := []struct {
+		input    Node
+		expected []string
+	}{
+		{&NumberLiteral{Value: 1}, nil},
+		{&ImportStatement{Path: str("lib.mk"), Name: &Identifier{Value: "lib"}}, []string{`"lib.mk"`, "lib"}},
+		{&IfExpression{Condition: &Identifier{Value: "c"}, Consequence: &Block{}}, []string{"c", "block"}},
+		{&HashLiteral{Pairs: map[Expression]Expression{
+			str("b"): number(2),
+			str("a"): number(1),
+		}}, []string{`"a"`, "1", `"b"`, "2"}},
+	}
+
+	for i, test := range tests {
+		var actual []string
+		for _, child := range Children(test.input) {
+			actual = append(actual, label(child))
+		}
+		assertions.AssertDeepEquals(t, test.expected, actual, "test["+strconv.Itoa(i)+"] - Children() wrong")
+	}
+}
@@ -0,0 +1,269 @@
+package monkey
+
+import (
+	"fmt"
+	"github.com/digital-codex/monkey/evaluator"
+	"github.com/digital-codex/monkey/object"
+	"math"
+	"reflect"
+	"strings"
+)
+
+var (
+	errorType  = reflect.TypeOf((*error)(nil)).Elem()
+	objectType = reflect.TypeOf((*object.Object)(nil)).Elem()
+)
+
+/*****************************************************************************
+ *                              PUBLIC FUNCTIONS                             *
+ *****************************************************************************/
+
+// Bind wraps the Go function fn in an object.Builtin. Arguments are converted
+// to the parameter types of fn, which may be numbers, strings, bools, slices,
+// arrays, maps, structs, pointers to those, any or object.Object. A trailing
+// error result that is not nil becomes an object.Error; the remaining results
+// are converted by ToObject, several of them into an object.Array.
+func Bind(fn any) (*object.Builtin, error) {
+	fv := reflect.ValueOf(fn)
+	if fv.Kind() != reflect.Func || fv.IsNil() {
+		return nil, fmt.Errorf("cannot bind %T: not a function", fn)
+	}
+	ft := fv.Type()
+
+	arity := ft.NumIn()
+	if ft.IsVariadic() {
+		arity = object.VARIADIC
+	}
+
+	// the results are converted from Go values anew on every call
+	builtin := &object.Builtin{Arity: arity, Allocates: true}
+	builtin.Fn = func(args ...object.Object) object.Object {
+		in, err := bindArguments(ft, args)
+		if err != nil {
+			return &object.Error{Message: err.Error()}
+		}
+		return bindResults(ft, fv.Call(in))
+	}
+
+	return builtin, nil
+}
+
+// Bind registers the Go function fn as the builtin name, see Bind.
+func (i *Interpreter) Bind(name string, doc string, fn any) error {
+	builtin, err := Bind(fn)
+	if err != nil {
+		return err
+	}
+	i.evaluator.Builtins.Register(name, builtin.Arity, doc, builtin.Fn)
+	i.evaluator.Builtins.Allocates(name)
+	return nil
+}
+
+/*****************************************************************************
+ *                             PRIVATE FUNCTIONS                             *
+ *****************************************************************************/
+
+func bindArguments(ft reflect.Type, args []object.Object) ([]reflect.Value, error) {
+	fixed := ft.NumIn()
+	if ft.IsVariadic() {
+		fixed--
+		if len(args) < fixed {
+			return nil, fmt.Errorf("wrong number of arguments. got=%d, want at least %d", len(args), fixed)
+		}
+	}
+
+	in := make([]reflect.Value, len(args))
+	for n, arg := range args {
+		var t reflect.Type
+		if n < fixed {
+			t = ft.In(n)
+		} else {
+			t = ft.In(fixed).Elem()
+		}
+
+		v, err := convertObject(arg, t)
+		if err != nil {
+			return nil, fmt.Errorf("argument %d: %s", n+1, err)
+		}
+		in[n] = v
+	}
+
+	return in, nil
+}
+
+func bindResults(ft reflect.Type, out []reflect.Value) object.Object {
+	if n := ft.NumOut(); n > 0 && ft.Out(n-1) == errorType {
+		if err, _ := out[n-1].Interface().(error); err != nil {
+			return &object.Error{Message: err.Error()}
+		}
+		out = out[:n-1]
+	}
+
+	results := make([]object.Object, len(out))
+	for n, v := range out {
+		obj, err := ToObject(v.Interface())
+		if err != nil {
+			return &object.Error{Message: err.Error()}
+		}
+		results[n] = obj
+	}
+
+	switch len(results) {
+	case 0:
+		return evaluator.NULL
+	case 1:
+		return results[0]
+	default:
+		return &object.Array{Elements: results}
+	}
+}
+
+func convertObject(obj object.Object, t reflect.Type) (reflect.Value, error) {
+	if t == objectType || reflect.TypeOf(obj) == t {
+		return reflect.ValueOf(obj), nil
+	}
+	if t.Kind() == reflect.Interface && t.NumMethod() > 0 && reflect.TypeOf(obj).Implements(t) {
+		return reflect.ValueOf(obj), nil
+	}
+
+	if t.Kind() == reflect.Pointer {
+		if obj.Type() == object.NULL {
+			return reflect.Zero(t), nil
+		}
+		elem, err := convertObject(obj, t.Elem())
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		ptr := reflect.New(t.Elem())
+		ptr.Elem().Set(elem)
+		return ptr, nil
+	}
+
+	v := reflect.New(t).Elem()
+	switch t.Kind() {
+	case reflect.Interface:
+		native, err := FromObject(obj)
+		if err != nil {
+			// functions, quotes and the like have no native equivalent
+			native = obj
+		}
+		if native == nil {
+			return v, nil
+		}
+		if !reflect.TypeOf(native).AssignableTo(t) {
+			return reflect.Value{}, cannotUse(obj, t)
+		}
+		v.Set(reflect.ValueOf(native))
+	case reflect.Bool:
+		b, ok := obj.(*object.Boolean)
+		if !ok {
+			return reflect.Value{}, cannotUse(obj, t)
+		}
+		v.SetBool(b.Value)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, ok := obj.(*object.Number)
+		// the range is checked before converting, which is undefined outside it
+		if !ok || n.Value != math.Trunc(n.Value) || n.Value < math.MinInt64 || n.Value >= -math.MinInt64 || v.OverflowInt(int64(n.Value)) {
+			return reflect.Value{}, cannotUse(obj, t)
+		}
+		v.SetInt(int64(n.Value))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		n, ok := obj.(*object.Number)
+		if !ok || n.Value < 0 || n.Value != math.Trunc(n.Value) || n.Value >= math.MaxUint64+1 || v.OverflowUint(uint64(n.Value)) {
+			return reflect.Value{}, cannotUse(obj, t)
+		}
+		v.SetUint(uint64(n.Value))
+	case reflect.Float32, reflect.Float64:
+		n, ok := obj.(*object.Number)
+		if !ok {
+			return reflect.Value{}, cannotUse(obj, t)
+		}
+		v.SetFloat(n.Value)
+	case reflect.String:
+		s, ok := obj.(*object.String)
+		if !ok {
+			return reflect.Value{}, cannotUse(obj, t)
+		}
+		v.SetString(s.Value)
+	case reflect.Slice, reflect.Array:
+		a, ok := obj.(*object.Array)
+		if !ok {
+			return reflect.Value{}, cannotUse(obj, t)
+		}
+		if t.Kind() == reflect.Slice {
+			v.Set(reflect.MakeSlice(t, len(a.Elements), len(a.Elements)))
+		} else if t.Len() != len(a.Elements) {
+			return reflect.Value{}, fmt.Errorf("cannot use array of length %d as %s", len(a.Elements), t)
+		}
+		for n, elem := range a.Elements {
+			ev, err := convertObject(elem, t.Elem())
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			v.Index(n).Set(ev)
+		}
+	case reflect.Map:
+		h, ok := obj.(*object.Hash)
+		if !ok {
+			return reflect.Value{}, cannotUse(obj, t)
+		}
+		v.Set(reflect.MakeMapWithSize(t, len(h.Pairs)))
+		for _, pair := range h.Pairs {
+			key, err := convertObject(pair.Key, t.Key())
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			value, err := convertObject(pair.Value, t.Elem())
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			v.SetMapIndex(key, value)
+		}
+	case reflect.Struct:
+		h, ok := obj.(*object.Hash)
+		if !ok {
+			return reflect.Value{}, cannotUse(obj, t)
+		}
+		for n := 0; n < t.NumField(); n++ {
+			name, ok := fieldName(t.Field(n))
+			if !ok {
+				continue
+			}
+			pair, ok := h.Pairs[(&object.String{Value: name}).HashKey()]
+			if !ok {
+				continue
+			}
+			fv, err := convertObject(pair.Value, t.Field(n).Type)
+			if err != nil {
+				return reflect.Value{}, fmt.Errorf("field %s: %s", name, err)
+			}
+			v.Field(n).Set(fv)
+		}
+	default:
+		return reflect.Value{}, cannotUse(obj, t)
+	}
+
+	return v, nil
+}
+
+// fieldName returns the hash key of an exported struct field: the name given
+// by its `monkey` tag, or else the field name itself.
+func fieldName(field reflect.StructField) (string, bool) {
+	if !field.IsExported() {
+		return "", false
+	}
+
+	tag := field.Tag.Get("monkey")
+	if tag == "-" {
+		return "", false
+	}
+	if name, _, _ := strings.Cut(tag, ","); name != "" {
+		return name, true
+	}
+
+	return field.Name, true
+}
+
+func cannotUse(obj object.Object, t reflect.Type) error {
+	return fmt.Errorf("cannot use %s as %s", obj.Type(), t)
+}
@@ -0,0 +1,134 @@
+package monkey
+
+import (
+	"errors"
+	"github.com/digital-codex/assertions"
+	"github.com/digital-codex/monkey/object"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+type point struct {
+	X     int
+	Y     int    `monkey:"y"`
+	Label string `monkey:"-"`
+	skip  bool
+}
+
+func TestBind(t *testing.T) {
+	interpreter := New(nil)
+	bindings := []struct {
+		name string
+		fn   any
+	}{
+		{"upper", strings.ToUpper},
+		{"repeat", strings.Repeat},
+		{"sum", func(xs ...float64) float64 {
+			total := 0.0
+			for _, x := range xs {
+				total += x
+			}
+			return total
+		}},
+		{"join", func(sep string, parts []string) string { return strings.Join(parts, sep) }},
+		{"keys", func(m map[string]int) int { return len(m) }},
+		{"norm", func(p point) int { return p.X*p.X + p.Y*p.Y }},
+		{"move", func(p *point, dx int) point { return point{X: p.X + dx, Y: p.Y} }},
+		{"divmod", func(a, b int) (int, int) { return a / b, a % b }},
+		{"check", func(ok bool) (string, error) {
+			if !ok {
+				return "", errors.New("check failed")
+			}
+			return "passed", nil
+		}},
+		{"noop", func() {}},
+		{"kind", func(v any) string {
+			switch v.(type) {
+			case float64:
+				return "number"
+			case object.Object:
+				return "object"
+			default:
+				return "other"
+			}
+		}},
+		{"raw", func(a *object.Array) object.Object { return a.Elements[0] }},
+	}
+	for _, binding := range bindings {
+		if err := interpreter.Bind(binding.name, "", binding.fn); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`upper("monkey")`, "MONKEY"},
+		{`repeat("ab", 3)`, "ababab"},
+		{`sum()`, "0.000000"},
+		{`sum(1, 2, 3.5)`, "6.500000"},
+		{`join("-", ["a", "b", "c"])`, "a-b-c"},
+		{`keys({"a": 1, "b": 2})`, "2.000000"},
+		{`norm({"X": 3, "y": 4})`, "25.000000"},
+		{`move({"X": 1, "y": 2}, 2)["X"]`, "3.000000"},
+		{`divmod(7, 2)`, "[3.000000, 1.000000]"},
+		{`check(true)`, "passed"},
+		{`noop()`, "null"},
+		{`kind(1)`, "number"},
+		{`kind(fn(x) { x })`, "object"},
+		{`raw([7])`, "7.000000"},
+	}
+
+	for i, test := range tests {
+		evaluated, err := interpreter.Run(test.input)
+		if err != nil {
+			t.Fatalf("test[%d] - unexpected error: %s", i, err)
+		}
+		assertions.AssertStringEquals(t, test.expected, evaluated.Inspect(), "test["+strconv.Itoa(i)+"] - result wrong")
+	}
+}
+
+func TestBindErrors(t *testing.T) {
+	interpreter := New(nil)
+	_ = interpreter.Bind("upper", "", strings.ToUpper)
+	_ = interpreter.Bind("byte", "", func(b uint8) uint8 { return b })
+	_ = interpreter.Bind("count", "", func(n int) int { return n })
+	_ = interpreter.Bind("signed", "", func(n int64) int64 { return n })
+	_ = interpreter.Bind("unsigned", "", func(n uint64) uint64 { return n })
+	_ = interpreter.Bind("pair", "", func(a [2]int) int { return a[0] + a[1] })
+	_ = interpreter.Bind("check", "", func(ok bool) error {
+		if !ok {
+			return errors.New("check failed")
+		}
+		return nil
+	})
+	_ = interpreter.Bind("at_least", "", func(first string, rest ...string) int { return len(rest) })
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`upper(1)`, "argument 1: cannot use NUMBER as string"},
+		{`upper("a", "b")`, "wrong number of arguments. got=2, want=1"},
+		{`byte(256)`, "argument 1: cannot use NUMBER as uint8"},
+		{`count(1.5)`, "argument 1: cannot use NUMBER as int"},
+		{`signed(9223372036854775808)`, "argument 1: cannot use NUMBER as int64"},
+		{`signed(-10000000000000000000)`, "argument 1: cannot use NUMBER as int64"},
+		{`unsigned(18446744073709551616)`, "argument 1: cannot use NUMBER as uint64"},
+		{`unsigned(100000000000000000000)`, "argument 1: cannot use NUMBER as uint64"},
+		{`pair([1])`, "argument 1: cannot use array of length 1 as [2]int"},
+		{`check(false)`, "check failed"},
+		{`at_least()`, "wrong number of arguments. got=0, want at least 1"},
+	}
+
+	for i, test := range tests {
+		_, err := interpreter.Run(test.input)
+		assertions.AssertBoolEquals(t, true, err != nil, "test["+strconv.Itoa(i)+"] - expected error")
+		assertions.AssertStringEquals(t, test.expected, err.Error(), "test["+strconv.Itoa(i)+"] - err.Error() wrong")
+	}
+
+	_, err := Bind(42)
+	assertions.AssertStringEquals(t, "cannot bind int: not a function", err.Error(), "Bind(42) error wrong")
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/digital-codex/monkey/ast"
+	"github.com/digital-codex/monkey/parser"
+	"io"
+	"os"
+	"strings"
+)
+
+// runAST prints the syntax tree of a source file, as source code or with
+// --json in the schema of ast.ToJSON, and returns the exit code.
+func runAST(args []string, stdout io.Writer, stderr io.Writer) int {
+	fs := flag.NewFlagSet("ast", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	asJSON := fs.Bool("json", false, "print the tree as JSON")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 1 {
+		fmt.Fprintln(stderr, "usage: monkey ast [--json] file.mk")
+		return 2
+	}
+
+	src, err := os.ReadFile(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(stderr, "monkey ast: %s\n", err)
+		return 1
+	}
+
+	p := parser.New(string(src))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		for _, err := range p.Errors() {
+			fmt.Fprintf(stderr, "%s: %s\n", fs.Arg(0), strings.TrimSpace(err.Error()))
+		}
+		return 1
+	}
+
+	if !*asJSON {
+		fmt.Fprintln(stdout, program.String())
+		return 0
+	}
+
+	data, err := ast.ToJSON(program)
+	if err != nil {
+		fmt.Fprintf(stderr, "monkey ast: %s\n", err)
+		return 1
+	}
+	var out bytes.Buffer
+	_ = json.Indent(&out, data, "", "  ")
+	out.WriteByte('\n')
+	stdout.Write(out.Bytes())
+	return 0
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/digital-codex/monkey/format"
+	"io"
+	"os"
+)
+
+// runFmt formats the source files named in args, or standard input when there
+// are none, and returns the exit code. The formatted code is printed unless -w
+// writes it back to the files or -d prints how it differs instead.
+func runFmt(args []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) int {
+	fs := flag.NewFlagSet("fmt", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	write := fs.Bool("w", false, "write the result to the files instead of printing it")
+	diff := fs.Bool("d", false, "print a diff of the changes instead of the result")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	if fs.NArg() == 0 {
+		if *write {
+			fmt.Fprintln(stderr, "monkey fmt: cannot use -w with standard input")
+			return 2
+		}
+		src, err := io.ReadAll(stdin)
+		if err != nil {
+			fmt.Fprintf(stderr, "monkey fmt: %s\n", err)
+			return 1
+		}
+		if !formatFile("<stdin>", src, false, *diff, stdout, stderr) {
+			return 1
+		}
+		return 0
+	}
+
+	code := 0
+	for _, name := range fs.Args() {
+		src, err := os.ReadFile(name)
+		if err != nil {
+			fmt.Fprintf(stderr, "monkey fmt: %s\n", err)
+			code = 1
+			continue
+		}
+		if !formatFile(name, src, *write, *diff, stdout, stderr) {
+			code = 1
+		}
+	}
+	return code
+}
+
+// formatFile formats src, read from the file name, and reports whether it
+// succeeded.
+func formatFile(name string, src []byte, write bool, diff bool, stdout io.Writer, stderr io.Writer) bool {
+	formatted, err := format.Source(src)
+	if err != nil {
+		fmt.Fprintf(stderr, "%s:\n%s\n", name, err)
+		return false
+	}
+
+	if diff {
+		stdout.Write(format.Diff(name, src, formatted))
+	}
+	if write {
+		if string(formatted) == string(src) {
+			return true
+		}
+		info, err := os.Stat(name)
+		if err != nil {
+			fmt.Fprintf(stderr, "monkey fmt: %s\n", err)
+			return false
+		}
+		if err := os.WriteFile(name, formatted, info.Mode().Perm()); err != nil {
+			fmt.Fprintf(stderr, "monkey fmt: %s\n", err)
+			return false
+		}
+	}
+	if !diff && !write {
+		stdout.Write(formatted)
+	}
+	return true
+}
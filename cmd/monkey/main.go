@@ -0,0 +1,58 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+)
+
+const USAGE = `usage: monkey [-O] [-dump-ast] <command> [arguments]
+
+commands:
+	run file.mk [args]    run a script, or standard input for -, with args bound to args
+	eval -e code          evaluate code, or standard input for -, and print its value
+	repl                  start the interactive prompt, the default without a command
+	fmt [-w] [-d] files   format source files, or standard input without any
+	ast [--json] file.mk  print the syntax tree of a source file
+
+A script run from a file imports modules from its own directory, so an import
+relative to the working directory does not resolve. Standard input and eval
+import modules from the working directory.
+`
+
+func main() {
+	os.Exit(cli(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// cli runs the command named by the first of args and returns the exit code.
+func cli(args []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) int {
+	var opts options
+	fs := flag.NewFlagSet("monkey", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() { fmt.Fprint(stderr, USAGE) }
+	opts.register(fs)
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	if fs.NArg() == 0 {
+		return runREPL(opts, nil, stdin, stdout, stderr)
+	}
+	switch rest := fs.Args()[1:]; fs.Arg(0) {
+	case "run":
+		return runScript(opts, rest, stdin, stdout, stderr)
+	case "eval":
+		return runEval(opts, rest, stdin, stdout, stderr)
+	case "repl":
+		return runREPL(opts, rest, stdin, stdout, stderr)
+	case "fmt":
+		return runFmt(rest, stdin, stdout, stderr)
+	case "ast":
+		return runAST(rest, stdout, stderr)
+	default:
+		fmt.Fprintf(stderr, "monkey: unknown command %q\n", fs.Arg(0))
+		fs.Usage()
+		return 2
+	}
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"bytes"
+	"github.com/digital-codex/assertions"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestCLI(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"args.mk":     `puts(args);`,
+		"broken.mk":   `let x = ;`,
+		"failing.mk":  `first(1);`,
+		"main.mk":     `import "./lib.mk" as lib; puts(lib["double"](21));`,
+		"lib.mk":      `export let double = fn(x) { x * 2 };`,
+		"sub/main.mk": `import "./lib.mk" as lib; puts(lib["one"]);`,
+		"sub/lib.mk":  `export let one = 1;`,
+		"sub/cwd.mk":  `import "./sub/lib.mk" as lib;`,
+	}
+	for name, src := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	script := func(name string) string {
+		return filepath.Join(dir, name)
+	}
+
+	tests := []struct {
+		args   []string
+		stdin  string
+		code   int
+		stdout string
+		stderr string
+	}{
+		{[]string{"run", script("args.mk")}, "", 0, "[]\n", ""},
+		{[]string{"run", script("args.mk"), "a", "-b"}, "", 0, "[a, -b]\n", ""},
+		{[]string{"-O", "run", script("args.mk"), "a"}, "", 0, "[a]\n", ""},
+		{[]string{"run", "-", "a"}, `puts(args);`, 0, "[a]\n", ""},
+		{[]string{"run", "-"}, `puts(1 + 2);`, 0, "3.000000\n", ""},
+		{[]string{"run", script("main.mk")}, "", 0, "42.000000\n", ""},
+		{[]string{"run", script("sub/main.mk")}, "", 0, "1.000000\n", ""},
+		{[]string{"run", script("sub/cwd.mk")}, "", 1, "", script("sub/cwd.mk") + ": cannot import \"./sub/lib.mk\": open sub/lib.mk: no such file or directory\n"},
+		{[]string{"run", script("broken.mk")}, "", 1, "", script("broken.mk") + ": Error:1:8: expect expression got \";\"\n"},
+		{[]string{"run", script("failing.mk")}, "", 1, "", script("failing.mk") + ": argument to `first` not supported, got NUMBER\n"},
+		{[]string{"run", script("missing.mk")}, "", 1, "", script("missing.mk") + ": open missing.mk: no such file or directory\n"},
+		{[]string{"run", "-"}, `let x = ;`, 1, "", "<stdin>: Error:1:8: expect expression got \";\"\n"},
+		{[]string{"eval", "-e", "1 + 2"}, "", 0, "3.000000\n", ""},
+		{[]string{"eval", "-e", "let x = 1;"}, "", 0, "", ""},
+		{[]string{"eval", "-"}, `"a" + "b"`, 0, "ab\n", ""},
+		{[]string{"eval", "-e", "let x = ;"}, "", 1, "", "-e: Error:1:8: expect expression got \";\"\n"},
+		{[]string{"eval", "-e", "first(1)"}, "", 1, "", "-e: argument to `first` not supported, got NUMBER\n"},
+		{[]string{"run"}, "", 2, "", "usage: monkey run [-O] [-dump-ast] file.mk|- [args]\n"},
+		{[]string{"eval"}, "", 2, "", "usage: monkey eval [-O] [-dump-ast] -e code | -\n"},
+		{[]string{"eval", "-e", "1", "-"}, "", 2, "", "usage: monkey eval [-O] [-dump-ast] -e code | -\n"},
+		{[]string{"repl", "extra"}, "", 2, "", "usage: monkey repl [-O] [-dump-ast]\n"},
+		{[]string{"unknown"}, "", 2, "", "monkey: unknown command \"unknown\"\n" + USAGE},
+	}
+
+	for i, test := range tests {
+		var stdout, stderr bytes.Buffer
+		code := cli(test.args, strings.NewReader(test.stdin), &stdout, &stderr)
+
+		assertions.AssertIntEquals(t, test.code, code, "test["+strconv.Itoa(i)+"] - exit code wrong")
+		assertions.AssertStringEquals(t, test.stdout, stdout.String(), "test["+strconv.Itoa(i)+"] - stdout wrong")
+		assertions.AssertStringEquals(t, test.stderr, stderr.String(), "test["+strconv.Itoa(i)+"] - stderr wrong")
+	}
+}
+
+func TestCLIFlagErrors(t *testing.T) {
+	tests := [][]string{
+		{"-unknown"},
+		{"run", "-unknown", "file.mk"},
+		{"eval", "-unknown"},
+		{"repl", "-unknown"},
+	}
+
+	for i, args := range tests {
+		var stdout, stderr bytes.Buffer
+		code := cli(args, strings.NewReader(""), &stdout, &stderr)
+
+		assertions.AssertIntEquals(t, 2, code, "test["+strconv.Itoa(i)+"] - exit code wrong")
+		assertions.AssertBoolEquals(t, true, strings.Contains(stderr.String(), "-unknown"), "test["+strconv.Itoa(i)+"] - stderr wrong")
+	}
+}
+
+func TestCLIREPL(t *testing.T) {
+	tests := [][]string{
+		{"repl"},
+		{},
+	}
+
+	for i, args := range tests {
+		var stdout, stderr bytes.Buffer
+		code := cli(args, strings.NewReader("let x = 1 + 2;\nx\n"), &stdout, &stderr)
+
+		assertions.AssertIntEquals(t, 0, code, "test["+strconv.Itoa(i)+"] - exit code wrong")
+		assertions.AssertBoolEquals(t, true, strings.HasSuffix(stdout.String(), ">> >> 3.000000\n>> "), "test["+strconv.Itoa(i)+"] - stdout wrong")
+		assertions.AssertStringEquals(t, "", stderr.String(), "test["+strconv.Itoa(i)+"] - stderr wrong")
+	}
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/digital-codex/monkey"
+	"github.com/digital-codex/monkey/object"
+	"github.com/digital-codex/monkey/repl"
+	"io"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+)
+
+// options are the interpreter flags shared by run, eval and repl, which may be
+// given before the command or after it.
+type options struct {
+	optimize bool
+	dumpAST  bool
+}
+
+func (o *options) register(fs *flag.FlagSet) {
+	fs.BoolVar(&o.optimize, "O", o.optimize, "optimize programs before evaluating them")
+	fs.BoolVar(&o.dumpAST, "dump-ast", o.dumpAST, "print the optimized program to standard error; implies -O")
+}
+
+// interpreter returns an interpreter importing modules from dir.
+func (o *options) interpreter(dir string, stdin io.Reader, stdout io.Writer, stderr io.Writer) *monkey.Interpreter {
+	interpreter := monkey.New(os.DirFS(dir))
+	interpreter.SetStdin(stdin)
+	interpreter.SetStdout(stdout)
+	interpreter.SetStderr(stderr)
+	if o.dumpAST {
+		interpreter.EnableOptimizer(stderr)
+	} else if o.optimize {
+		interpreter.EnableOptimizer(nil)
+	}
+	return interpreter
+}
+
+// runScript runs the script named by the first of args, or read from standard
+// input when it is "-", with the rest bound to the array args, and returns the
+// exit code.
+func runScript(opts options, args []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) int {
+	fs := flag.NewFlagSet("run", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	opts.register(fs)
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() == 0 {
+		fmt.Fprintln(stderr, "usage: monkey run [-O] [-dump-ast] file.mk|- [args]")
+		return 2
+	}
+
+	name := fs.Arg(0)
+	if name == "-" {
+		src, err := io.ReadAll(stdin)
+		if err != nil {
+			fmt.Fprintf(stderr, "monkey run: %s\n", err)
+			return 1
+		}
+		interpreter := opts.interpreter(".", stdin, stdout, stderr)
+		if !setArgs(interpreter, fs.Args()[1:], stderr) {
+			return 1
+		}
+		_, err = interpreter.Run(string(src))
+		return report(stderr, "<stdin>", err)
+	}
+
+	// the script imports modules from its own directory
+	interpreter := opts.interpreter(filepath.Dir(name), stdin, stdout, stderr)
+	if !setArgs(interpreter, fs.Args()[1:], stderr) {
+		return 1
+	}
+	_, err := interpreter.RunFile(filepath.Base(name))
+	return report(stderr, name, err)
+}
+
+// runEval evaluates the code given with -e, or read from standard input when
+// the only argument is "-", prints its value unless it is null and returns the
+// exit code.
+func runEval(opts options, args []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) int {
+	fs := flag.NewFlagSet("eval", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	opts.register(fs)
+	code := fs.String("e", "", "the code to evaluate")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	name := "-e"
+	src := *code
+	switch {
+	case fs.NArg() == 0 && *code != "":
+	case fs.NArg() == 1 && fs.Arg(0) == "-" && *code == "":
+		data, err := io.ReadAll(stdin)
+		if err != nil {
+			fmt.Fprintf(stderr, "monkey eval: %s\n", err)
+			return 1
+		}
+		name = "<stdin>"
+		src = string(data)
+	default:
+		fmt.Fprintln(stderr, "usage: monkey eval [-O] [-dump-ast] -e code | -")
+		return 2
+	}
+
+	evaluated, err := opts.interpreter(".", stdin, stdout, stderr).Run(src)
+	if code := report(stderr, name, err); code != 0 {
+		return code
+	}
+	switch evaluated.(type) {
+	case nil, *object.Null:
+	default:
+		fmt.Fprintln(stdout, evaluated.Inspect())
+	}
+	return 0
+}
+
+// runREPL starts the interactive prompt and returns the exit code once its
+// input ends.
+func runREPL(opts options, args []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) int {
+	fs := flag.NewFlagSet("repl", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	opts.register(fs)
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 0 {
+		fmt.Fprintln(stderr, "usage: monkey repl [-O] [-dump-ast]")
+		return 2
+	}
+
+	current, err := user.Current()
+	if err != nil {
+		current = &user.User{Username: os.Getenv("USER"), HomeDir: os.Getenv("HOME")}
+	}
+
+	repl.Start(stdin, stdout, opts.interpreter(".", stdin, stdout, stderr), current)
+	return 0
+}
+
+// setArgs binds the script arguments to args and reports whether it succeeded.
+func setArgs(interpreter *monkey.Interpreter, args []string, stderr io.Writer) bool {
+	if args == nil {
+		args = []string{}
+	}
+	if err := interpreter.Set("args", args); err != nil {
+		fmt.Fprintf(stderr, "monkey: %s\n", err)
+		return false
+	}
+	return true
+}
+
+// report prints each message of err, if any, prefixed by the name of the code
+// that failed and returns the exit code for it.
+func report(stderr io.Writer, name string, err error) int {
+	if err == nil {
+		return 0
+	}
+	for _, msg := range strings.Split(strings.TrimSpace(err.Error()), "\n") {
+		fmt.Fprintf(stderr, "%s: %s\n", name, msg)
+	}
+	return 1
+}
@@ -0,0 +1,146 @@
+package monkey
+
+import (
+	"fmt"
+	"github.com/digital-codex/monkey/evaluator"
+	"github.com/digital-codex/monkey/object"
+	"reflect"
+)
+
+/*****************************************************************************
+ *                              PUBLIC FUNCTIONS                             *
+ *****************************************************************************/
+
+// ToObject converts a Go value into its Monkey equivalent. Numeric kinds become
+// object.Number, slices and arrays become object.Array, and maps and structs
+// become object.Hash, keyed by field name or `monkey` tag for the latter.
+// Values that already are an object.Object are returned as is.
+func ToObject(v any) (object.Object, error) {
+	switch v := v.(type) {
+	case nil:
+		return evaluator.NULL, nil
+	case object.Object:
+		return v, nil
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Bool:
+		if rv.Bool() {
+			return evaluator.TRUE, nil
+		}
+		return evaluator.FALSE, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return &object.Number{Value: float64(rv.Int())}, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return &object.Number{Value: float64(rv.Uint())}, nil
+	case reflect.Float32, reflect.Float64:
+		return &object.Number{Value: rv.Float()}, nil
+	case reflect.String:
+		return &object.String{Value: rv.String()}, nil
+	case reflect.Slice, reflect.Array:
+		elements := make([]object.Object, rv.Len())
+		for i := range elements {
+			elem, err := ToObject(rv.Index(i).Interface())
+			if err != nil {
+				return nil, err
+			}
+			elements[i] = elem
+		}
+		return &object.Array{Elements: elements}, nil
+	case reflect.Map:
+		pairs := make(map[object.HashKey]object.HashPair)
+		iter := rv.MapRange()
+		for iter.Next() {
+			key, err := ToObject(iter.Key().Interface())
+			if err != nil {
+				return nil, err
+			}
+			hashKey, ok := key.(object.Hashable)
+			if !ok {
+				return nil, fmt.Errorf("unusable as hash key: %s", key.Type())
+			}
+			value, err := ToObject(iter.Value().Interface())
+			if err != nil {
+				return nil, err
+			}
+			pairs[hashKey.HashKey()] = object.HashPair{Key: key, Value: value}
+		}
+		return &object.Hash{Pairs: pairs}, nil
+	case reflect.Struct:
+		pairs := make(map[object.HashKey]object.HashPair)
+		for n := 0; n < rv.NumField(); n++ {
+			name, ok := fieldName(rv.Type().Field(n))
+			if !ok {
+				continue
+			}
+			value, err := ToObject(rv.Field(n).Interface())
+			if err != nil {
+				return nil, err
+			}
+			key := &object.String{Value: name}
+			pairs[key.HashKey()] = object.HashPair{Key: key, Value: value}
+		}
+		return &object.Hash{Pairs: pairs}, nil
+	case reflect.Pointer, reflect.Interface:
+		if rv.IsNil() {
+			return evaluator.NULL, nil
+		}
+		return ToObject(rv.Elem().Interface())
+	default:
+		return nil, fmt.Errorf("cannot convert %T to a Monkey object", v)
+	}
+}
+
+// FromObject converts a Monkey value into its Go equivalent: float64, string,
+// bool, nil, []any or map[any]any. Modules convert to map[string]any.
+func FromObject(obj object.Object) (any, error) {
+	switch obj := obj.(type) {
+	case nil, *object.Null:
+		return nil, nil
+	case *object.Number:
+		return obj.Value, nil
+	case *object.String:
+		return obj.Value, nil
+	case *object.Boolean:
+		return obj.Value, nil
+	case *object.ReturnValue:
+		return FromObject(obj.Value)
+	case *object.Array:
+		elements := make([]any, len(obj.Elements))
+		for i, elem := range obj.Elements {
+			v, err := FromObject(elem)
+			if err != nil {
+				return nil, err
+			}
+			elements[i] = v
+		}
+		return elements, nil
+	case *object.Hash:
+		pairs := make(map[any]any, len(obj.Pairs))
+		for _, pair := range obj.Pairs {
+			key, err := FromObject(pair.Key)
+			if err != nil {
+				return nil, err
+			}
+			value, err := FromObject(pair.Value)
+			if err != nil {
+				return nil, err
+			}
+			pairs[key] = value
+		}
+		return pairs, nil
+	case *object.Module:
+		exports := make(map[string]any, len(obj.Exports))
+		for name, export := range obj.Exports {
+			v, err := FromObject(export)
+			if err != nil {
+				return nil, err
+			}
+			exports[name] = v
+		}
+		return exports, nil
+	default:
+		return nil, fmt.Errorf("cannot convert %s to a Go value", obj.Type())
+	}
+}
@@ -0,0 +1,69 @@
+package monkey
+
+import (
+	"github.com/digital-codex/assertions"
+	"github.com/digital-codex/monkey/evaluator"
+	"github.com/digital-codex/monkey/object"
+	"strconv"
+	"testing"
+)
+
+func TestToObject(t *testing.T) {
+	answer := 42
+	tests := []struct {
+		input    any
+		expected string
+	}{
+		{nil, "null"},
+		{true, "true"},
+		{7, "7.000000"},
+		{uint8(7), "7.000000"},
+		{float32(0.5), "0.500000"},
+		{"monkey", "monkey"},
+		{[]string{"a", "b"}, "[a, b]"},
+		{[2]bool{true, false}, "[true, false]"},
+		{map[string]int{"one": 1}, "{one:1.000000}"},
+		{&answer, "42.000000"},
+		{(*int)(nil), "null"},
+		{&object.String{Value: "as is"}, "as is"},
+		{struct{ Name string }{"monkey"}, "{Name:monkey}"},
+		{struct {
+			Name  string `monkey:"name"`
+			Label string `monkey:"-"`
+		}{"monkey", "hidden"}, "{name:monkey}"},
+	}
+
+	for i, test := range tests {
+		obj, err := ToObject(test.input)
+		if err != nil {
+			t.Fatalf("test[%d] - unexpected error: %s", i, err)
+		}
+		assertions.AssertStringEquals(t, test.expected, obj.Inspect(), "test["+strconv.Itoa(i)+"] - obj.Inspect() wrong")
+	}
+
+	obj, _ := ToObject(false)
+	assertions.AssertEquals(t, evaluator.FALSE, obj, "false should convert to evaluator.FALSE")
+
+	_, err := ToObject(map[[1]int]int{{1}: 1})
+	assertions.AssertStringEquals(t, "unusable as hash key: ARRAY", err.Error(), "array key error wrong")
+	_, err = ToObject(func() {})
+	assertions.AssertBoolEquals(t, true, err != nil, "func should not convert")
+}
+
+func TestFromObject(t *testing.T) {
+	interpreter := New(nil)
+	evaluated, err := interpreter.Run(`[1, "two", true, {"k": [if (false) { 1 }]}]`)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	actual, err := FromObject(evaluated)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	assertions.AssertDeepEquals(t, []any{1.0, "two", true, map[any]any{"k": []any{nil}}}, actual, "FromObject() wrong")
+
+	evaluated, _ = interpreter.Run(`fn(x) { x }`)
+	_, err = FromObject(evaluated)
+	assertions.AssertStringEquals(t, "cannot convert FUNCTION to a Go value", err.Error(), "FromObject() error wrong")
+}
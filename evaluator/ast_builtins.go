@@ -0,0 +1,84 @@
+package evaluator
+
+import (
+	"github.com/digital-codex/monkey/ast"
+	"github.com/digital-codex/monkey/lexer"
+	"github.com/digital-codex/monkey/object"
+	"github.com/digital-codex/monkey/token"
+)
+
+/*****************************************************************************
+ *                             PRIVATE FUNCTIONS                             *
+ *****************************************************************************/
+
+// isIdentifier reports whether name is lexed as a single identifier, which
+// keywords are not.
+func isIdentifier(name string) bool {
+	l := lexer.New(name, nil)
+	t := l.Next()
+	return t.Type == token.IDENT && t.Lexeme == name && l.Next().Type == token.EOF
+}
+
+/*****************************************************************************
+ *                                 BUILTINS                                  *
+ *****************************************************************************/
+
+func builtinAstKind(args ...object.Object) object.Object {
+	quote, ok := args[0].(*object.Quote)
+	if !ok {
+		return makeError("argument to `ast_kind` must be QUOTE, got %s", args[0].Type())
+	}
+
+	return &object.String{Value: ast.Kind(quote.Node)}
+}
+
+func builtinAstChildren(args ...object.Object) object.Object {
+	quote, ok := args[0].(*object.Quote)
+	if !ok {
+		return makeError("argument to `ast_children` must be QUOTE, got %s", args[0].Type())
+	}
+
+	children := ast.Children(quote.Node)
+	elements := make([]object.Object, len(children))
+	for i, child := range children {
+		elements[i] = &object.Quote{Node: child}
+	}
+	return &object.Array{Elements: elements}
+}
+
+func builtinAstMakeCall(args ...object.Object) object.Object {
+	function, ok := convertObjectToNode(args[0]).(ast.Expression)
+	if !ok {
+		return makeError("cannot call %s in `ast_make_call`", args[0].Type())
+	}
+	array, ok := args[1].(*object.Array)
+	if !ok {
+		return makeError("second argument to `ast_make_call` must be ARRAY, got %s", args[1].Type())
+	}
+
+	arguments := make([]ast.Expression, len(array.Elements))
+	for i, elem := range array.Elements {
+		arguments[i], ok = convertObjectToNode(elem).(ast.Expression)
+		if !ok {
+			return makeError("cannot pass %s as an argument in `ast_make_call`", elem.Type())
+		}
+	}
+
+	return &object.Quote{Node: &ast.CallExpression{
+		Token:    token.Token{Type: token.LPAREN, Lexeme: "("},
+		Function: function,
+		Argument: arguments,
+	}}
+}
+
+func builtinAstIdent(args ...object.Object) object.Object {
+	name, ok := args[0].(*object.String)
+	if !ok {
+		return makeError("argument to `ast_ident` must be STRING, got %s", args[0].Type())
+	}
+	if !isIdentifier(name.Value) {
+		return makeError("invalid identifier %q", name.Value)
+	}
+
+	return &object.Quote{Node: &ast.Identifier{Token: token.Token{Type: token.IDENT, Lexeme: name.Value}, Value: name.Value}}
+}
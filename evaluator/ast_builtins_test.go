@@ -0,0 +1,56 @@
+package evaluator
+
+import (
+	"github.com/digital-codex/assertions"
+	"github.com/digital-codex/monkey/object"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func TestAstBuiltins(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`ast_kind(quote(f(1)))`, `call`},
+		{`ast_kind(quote(x))`, `identifier`},
+		{`ast_kind(quote(unquote(null)))`, `null`},
+		{`ast_kind(last(ast_children(quote(fn(x) { x }))))`, `block`},
+		{`ast_children(quote(a + b))`, `[QUOTE(a), QUOTE(b)]`},
+		{`ast_children(quote(f(1, x)))`, `[QUOTE(f), QUOTE(1), QUOTE(x)]`},
+		{`ast_children(quote({"b": 2, "a": 1}))`, `[QUOTE(a), QUOTE(1), QUOTE(b), QUOTE(2)]`},
+		{`ast_children(quote(1))`, `[]`},
+		{`ast_ident("foo")`, `QUOTE(foo)`},
+		{`ast_make_call(ast_ident("max"), [1, quote(x)])`, `QUOTE(max(1.000000, x))`},
+		{`ast_make_call(quote(fn(a) { a }), [])`, `QUOTE(fn(a){a}())`},
+	}
+
+	for i, test := range tests {
+		evaluated := eval(test.input)
+		assertions.AssertStringEquals(t, test.expected, evaluated.Inspect(), "test["+strconv.Itoa(i)+"] - evaluated.Inspect() wrong")
+	}
+}
+
+func TestAstBuiltinsErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`ast_kind(1)`, "argument to `ast_kind` must be QUOTE, got NUMBER"},
+		{`ast_children("a + b")`, "argument to `ast_children` must be QUOTE, got STRING"},
+		{`ast_ident(1)`, "argument to `ast_ident` must be STRING, got NUMBER"},
+		{`ast_ident("if")`, `invalid identifier "if"`},
+		{`ast_ident("a b")`, `invalid identifier "a b"`},
+		{`ast_make_call(len, [])`, "cannot call BUILTIN in `ast_make_call`"},
+		{`ast_make_call(quote(f), 1)`, "second argument to `ast_make_call` must be ARRAY, got NUMBER"},
+		{`ast_make_call(quote(f), [fn() { 1 }])`, "cannot pass FUNCTION as an argument in `ast_make_call`"},
+		{`quote(unquote(last(ast_children(quote(fn() { 1 })))))`, "cannot unquote a block node into an expression"},
+	}
+
+	for i, test := range tests {
+		evaluated := eval(test.input)
+		assertions.AssertTypeOf(t, reflect.TypeOf(object.Error{}), evaluated, "test["+strconv.Itoa(i)+"] - unexpected type")
+		assertions.AssertStringEquals(t, test.expected, evaluated.(*object.Error).Message, "test["+strconv.Itoa(i)+"] - err.Message wrong")
+	}
+}
@@ -2,105 +2,302 @@ package evaluator
 
 import (
 	"fmt"
+	"github.com/digital-codex/monkey/ast"
 	"github.com/digital-codex/monkey/object"
+	"io"
+	"math"
+	"sort"
 )
 
-var builtins = map[string]*object.Builtin{
-	"len": {
-		Fn: func(args ...object.Object) object.Object {
-			if len(args) != 1 {
-				return makeError("wrong number of arguments. got=%d, want=1", len(args))
-			}
-			switch arg := args[0].(type) {
-			case *object.Array:
-				return &object.Number{Value: float64(len(arg.Elements))}
-			case *object.String:
-				return &object.Number{Value: float64(len(arg.Value))}
-			default:
-				return makeError("argument to `len` not supported, got %s", args[0].Type())
-			}
-		},
-	},
-	"first": {
-		Fn: func(args ...object.Object) object.Object {
-			if len(args) != 1 {
-				return makeError("wrong number of arguments. got=%d, want=1", len(args))
-			}
-			if args[0].Type() != object.ARRAY {
-				return makeError("argument to `first` not supported, got %s", args[0].Type())
-			}
+/*****************************************************************************
+ *                                  TYPES                                    *
+ *****************************************************************************/
 
-			array := args[0].(*object.Array)
-			if len(array.Elements) > 0 {
-				return array.Elements[0]
-			}
+// Builtins is the set of native functions visible to the scripts run by an
+// Evaluator. Identifiers bound in the environment shadow builtins.
+type Builtins struct {
+	builtins map[string]*object.Builtin
+	requires map[string]Capability
+	streams  *Streams
+	expander *macroExpander
+}
 
-			return NULL
-		},
-	},
-	"last": {
-		Fn: func(args ...object.Object) object.Object {
-			if len(args) != 1 {
-				return makeError("wrong number of arguments. got=%d, want=1", len(args))
-			}
-			if args[0].Type() != object.ARRAY {
-				return makeError("argument to `last` not supported, got %s", args[0].Type())
-			}
+// macroExpander is the Evaluator whose macros the macroexpand builtin expands
+// with, the last one to expand a program with the registry.
+type macroExpander struct {
+	e *Evaluator
+}
 
-			array := args[0].(*object.Array)
-			length := len(array.Elements)
-			if length > 0 {
-				return array.Elements[length-1]
-			}
+/*****************************************************************************
+ *                              PUBLIC FUNCTIONS                             *
+ *****************************************************************************/
 
-			return NULL
-		},
-	},
-	"rest": {
-		Fn: func(args ...object.Object) object.Object {
-			if len(args) != 1 {
-				return makeError("wrong number of arguments. got=%d, want=1", len(args))
-			}
-			if args[0].Type() != object.ARRAY {
-				return makeError("argument to `rest` not supported, got %s", args[0].Type())
-			}
+func NewBuiltins() *Builtins {
+	return &Builtins{
+		builtins: make(map[string]*object.Builtin),
+		requires: make(map[string]Capability),
+		streams:  DefaultStreams(),
+		expander: &macroExpander{},
+	}
+}
 
-			array := args[0].(*object.Array)
-			length := len(array.Elements)
-			if length > 0 {
-				newArray := make([]object.Object, length-1)
-				copy(newArray, array.Elements[1:length])
-				return &object.Array{Elements: newArray}
-			}
+// DefaultBuiltins returns a new registry holding the standard builtins.
+func DefaultBuiltins() *Builtins {
+	b := NewBuiltins()
+	b.Register("len", 1, "len(x) returns the length of an array or a string.", builtinLen)
+	b.Register("first", 1, "first(array) returns the first element of array, or null if it is empty.", builtinFirst)
+	b.Register("last", 1, "last(array) returns the last element of array, or null if it is empty.", builtinLast)
+	b.Register("rest", 1, "rest(array) returns a new array holding every element of array but the first.", builtinRest)
+	b.Register("push", 2, "push(array, x) returns a new array holding the elements of array followed by x.", builtinPush)
+	b.Register("puts", object.VARIADIC, "puts(x...) prints each argument on its own line.", b.builtinPuts)
+	b.Register("print", object.VARIADIC, "print(x...) prints its arguments separated by spaces, without a trailing newline.", b.builtinPrint)
+	b.Register("eprint", object.VARIADIC, "eprint(x...) prints each argument on its own line to standard error.", b.builtinEprint)
+	b.Register("readLine", 0, "readLine() returns the next line of standard input, or null at its end.", b.builtinReadLine)
+	b.Register("gensym", object.VARIADIC, "gensym(prefix?) returns a new symbol, an identifier for use inside quote that no other name can capture.", builtinGensym)
+	b.Register("ast_kind", 1, "ast_kind(q) returns the kind of the node quoted by q, e.g. \"call\" or \"identifier\".", builtinAstKind)
+	b.Register("ast_children", 1, "ast_children(q) returns the quoted nodes directly below the node quoted by q.", builtinAstChildren)
+	b.Register("ast_make_call", 2, "ast_make_call(fn, args) returns the quoted call of fn with the elements of the array args.", builtinAstMakeCall)
+	b.Register("macroexpand", object.VARIADIC, "macroexpand(q, depth?) returns the code quoted by q with its macro calls expanded, up to depth levels when depth is given.", b.builtinMacroexpand)
+	b.Register("ast_ident", 1, "ast_ident(name) returns the quoted identifier called name.", builtinAstIdent)
+	b.Require("puts", STDOUT)
+	b.Require("print", STDOUT)
+	b.Require("eprint", STDERR)
+	b.Require("readLine", STDIN)
+	b.Allocates("rest")
+	b.Allocates("push")
+	b.Allocates("readLine")
+	b.Allocates("ast_kind")
+	b.Allocates("ast_children")
+	return b
+}
 
-			return NULL
-		},
-	},
-	"push": {
-		Fn: func(args ...object.Object) object.Object {
-			if len(args) != 2 {
-				return makeError("wrong number of arguments. got=%d, want=2", len(args))
-			}
-			if args[0].Type() != object.ARRAY {
-				return makeError("argument to `push` not supported, got %s", args[0].Type())
-			}
+// Register binds fn to name, replacing any builtin already registered under
+// it. Calls with a number of arguments other than arity are rejected before
+// fn runs, unless arity is object.VARIADIC.
+func (b *Builtins) Register(name string, arity int, doc string, fn object.BuiltinFunction) {
+	b.builtins[name] = &object.Builtin{Name: name, Arity: arity, Doc: doc, Fn: fn}
+}
+
+// Allocates marks the builtin name as returning new objects, which are counted
+// against the memory limit. The results of other builtins are taken to be
+// objects the script or the host already holds.
+func (b *Builtins) Allocates(name string) {
+	if builtin, ok := b.builtins[name]; ok {
+		builtin.Allocates = true
+	}
+}
+
+func (b *Builtins) Remove(name string) {
+	delete(b.builtins, name)
+	delete(b.requires, name)
+}
+
+// Streams returns the standard streams used by the I/O builtins of b, which
+// are shared with the registries returned by b.Scope.
+func (b *Builtins) Streams() *Streams {
+	return b.streams
+}
+
+func (b *Builtins) Get(name string) (*object.Builtin, bool) {
+	builtin, ok := b.builtins[name]
+	return builtin, ok
+}
+
+// Names returns the names of the registered builtins in sorted order.
+func (b *Builtins) Names() []string {
+	names := make([]string, 0, len(b.builtins))
+	for name := range b.builtins {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Scope returns a new registry holding only the named builtins of b. Names
+// that are not registered in b are ignored.
+func (b *Builtins) Scope(names ...string) *Builtins {
+	scoped := NewBuiltins()
+	scoped.streams = b.streams
+	scoped.expander = b.expander
+	for _, name := range names {
+		if builtin, ok := b.builtins[name]; ok {
+			scoped.builtins[name] = builtin
+			scoped.requires[name] = b.requires[name]
+		}
+	}
+	return scoped
+}
+
+/*****************************************************************************
+ *                             PRIVATE FUNCTIONS                             *
+ *****************************************************************************/
+
+func (e *Evaluator) isBuiltin(name string) bool {
+	if e.Builtins == nil {
+		return false
+	}
+	_, ok := e.Builtins.Get(name)
+	return ok
+}
+
+/*****************************************************************************
+ *                                 BUILTINS                                  *
+ *****************************************************************************/
 
-			array := args[0].(*object.Array)
-			length := len(array.Elements)
-			newArray := make([]object.Object, length+1)
-			copy(newArray, array.Elements)
-			newArray[length] = args[1]
-
-			return &object.Array{Elements: newArray}
-		},
-	},
-	"puts": {
-		Fn: func(args ...object.Object) object.Object {
-			for _, arg := range args {
-				fmt.Println(arg.Inspect())
+func builtinLen(args ...object.Object) object.Object {
+	switch arg := args[0].(type) {
+	case *object.Array:
+		return &object.Number{Value: float64(len(arg.Elements))}
+	case *object.String:
+		return &object.Number{Value: float64(len(arg.Value))}
+	default:
+		return makeError("argument to `len` not supported, got %s", args[0].Type())
+	}
+}
+
+func builtinFirst(args ...object.Object) object.Object {
+	if args[0].Type() != object.ARRAY {
+		return makeError("argument to `first` not supported, got %s", args[0].Type())
+	}
+
+	array := args[0].(*object.Array)
+	if len(array.Elements) > 0 {
+		return array.Elements[0]
+	}
+
+	return NULL
+}
+
+func builtinLast(args ...object.Object) object.Object {
+	if args[0].Type() != object.ARRAY {
+		return makeError("argument to `last` not supported, got %s", args[0].Type())
+	}
+
+	array := args[0].(*object.Array)
+	length := len(array.Elements)
+	if length > 0 {
+		return array.Elements[length-1]
+	}
+
+	return NULL
+}
+
+func builtinRest(args ...object.Object) object.Object {
+	if args[0].Type() != object.ARRAY {
+		return makeError("argument to `rest` not supported, got %s", args[0].Type())
+	}
+
+	array := args[0].(*object.Array)
+	length := len(array.Elements)
+	if length > 0 {
+		newArray := make([]object.Object, length-1)
+		copy(newArray, array.Elements[1:length])
+		return &object.Array{Elements: newArray}
+	}
+
+	return NULL
+}
+
+func builtinPush(args ...object.Object) object.Object {
+	if args[0].Type() != object.ARRAY {
+		return makeError("argument to `push` not supported, got %s", args[0].Type())
+	}
+
+	array := args[0].(*object.Array)
+	length := len(array.Elements)
+	newArray := make([]object.Object, length+1)
+	copy(newArray, array.Elements)
+	newArray[length] = args[1]
+
+	return &object.Array{Elements: newArray}
+}
+
+func builtinGensym(args ...object.Object) object.Object {
+	switch len(args) {
+	case 0:
+		return newSymbol("g")
+	case 1:
+		prefix, ok := args[0].(*object.String)
+		if !ok {
+			return makeError("argument to `gensym` not supported, got %s", args[0].Type())
+		}
+		return newSymbol(prefix.Value)
+	default:
+		return makeError("wrong number of arguments. got=%d, want=0 or 1", len(args))
+	}
+}
+
+func (b *Builtins) builtinMacroexpand(args ...object.Object) object.Object {
+	if len(args) != 1 && len(args) != 2 {
+		return makeError("wrong number of arguments. got=%d, want=1 or 2", len(args))
+	}
+	quote, ok := args[0].(*object.Quote)
+	if !ok {
+		return makeError("argument to `macroexpand` must be QUOTE, got %s", args[0].Type())
+	}
+	depth := 0
+	if len(args) == 2 {
+		n, ok := args[1].(*object.Number)
+		if !ok || n.Value < 1 || n.Value != math.Trunc(n.Value) {
+			return makeError("depth passed to `macroexpand` must be a positive integer, got %s", args[1].Inspect())
+		}
+		depth = int(n.Value)
+	}
+
+	expanded := ast.Clone(quote.Node)
+	e := b.expander.e
+	if e == nil || e.macros == nil {
+		return &object.Quote{Node: expanded}
+	}
+	x := &expander{e: e, limit: depth}
+	expanded = x.expand(expanded, e.macros, 0)
+	if len(x.errors) != 0 {
+		return makeError("%s", x.errors[0])
+	}
+	return &object.Quote{Node: expanded}
+}
+
+func (b *Builtins) builtinPuts(args ...object.Object) object.Object {
+	return writeLines(b.streams.Stdout, "stdout", args)
+}
+
+func (b *Builtins) builtinPrint(args ...object.Object) object.Object {
+	for i, arg := range args {
+		if i > 0 {
+			if _, err := io.WriteString(b.streams.Stdout, " "); err != nil {
+				return makeError("cannot write to stdout: %s", err)
 			}
+		}
+		if _, err := io.WriteString(b.streams.Stdout, arg.Inspect()); err != nil {
+			return makeError("cannot write to stdout: %s", err)
+		}
+	}
+
+	return NULL
+}
+
+func (b *Builtins) builtinEprint(args ...object.Object) object.Object {
+	return writeLines(b.streams.Stderr, "stderr", args)
+}
+
+func (b *Builtins) builtinReadLine(args ...object.Object) object.Object {
+	line, err := b.streams.readLine()
+	if err == io.EOF {
+		return NULL
+	}
+	if err != nil {
+		return makeError("cannot read from stdin: %s", err)
+	}
+
+	return &object.String{Value: line}
+}
+
+func writeLines(w io.Writer, name string, args []object.Object) object.Object {
+	for _, arg := range args {
+		if _, err := fmt.Fprintln(w, arg.Inspect()); err != nil {
+			return makeError("cannot write to %s: %s", name, err)
+		}
+	}
 
-			return NULL
-		},
-	},
+	return NULL
 }
@@ -0,0 +1,101 @@
+package evaluator
+
+import (
+	"github.com/digital-codex/assertions"
+	"github.com/digital-codex/monkey/object"
+	"github.com/digital-codex/monkey/parser"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func TestBuiltins(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected any
+	}{
+		{`first([1, 2, 3])`, 1},
+		{`first([])`, NULL},
+		{`last([1, 2, 3])`, 3},
+		{`last([])`, NULL},
+		{`len(rest([1, 2, 3]))`, 2},
+		{`rest([])`, NULL},
+		{`len(push([1, 2], 3))`, 3},
+		{`let len = fn(x) { 42 }; len([])`, 42},
+	}
+
+	for i, test := range tests {
+		evaluated := eval(test.input)
+		testObject(evaluated)(t, i, evaluated, test.expected)
+	}
+}
+
+func TestRegisterBuiltins(t *testing.T) {
+	double := func(args ...object.Object) object.Object {
+		return &object.Number{Value: args[0].(*object.Number).Value * 2}
+	}
+	sum := func(args ...object.Object) object.Object {
+		total := 0.0
+		for _, arg := range args {
+			total += arg.(*object.Number).Value
+		}
+		return &object.Number{Value: total}
+	}
+
+	builtins := DefaultBuiltins()
+	builtins.Register("double", 1, "double(x) returns x * 2.", double)
+	builtins.Register("sum", object.VARIADIC, "sum(x...) returns the sum of its arguments.", sum)
+	builtins.Remove("puts")
+
+	tests := []struct {
+		input    string
+		expected any
+	}{
+		{`double(21)`, 42},
+		{`sum()`, 0},
+		{`sum(1, 2, 3)`, 6},
+		{`len("abc")`, 3},
+	}
+
+	for i, test := range tests {
+		evaluated := evalWithBuiltins(test.input, builtins)
+		testObject(evaluated)(t, i, evaluated, test.expected)
+	}
+
+	builtin, ok := builtins.Get("double")
+	assertions.AssertBoolEquals(t, true, ok, "double should be registered")
+	assertions.AssertStringEquals(t, "double", builtin.Name, "builtin.Name wrong")
+	assertions.AssertIntEquals(t, 1, builtin.Arity, "builtin.Arity wrong")
+	assertions.AssertStringEquals(t, "double(x) returns x * 2.", builtin.Doc, "builtin.Doc wrong")
+	assertions.AssertDeepEquals(t, []string{"ast_children", "ast_ident", "ast_kind", "ast_make_call", "double", "eprint", "first", "gensym", "last", "len", "macroexpand", "print", "push", "readLine", "rest", "sum"}, builtins.Names(), "builtins.Names() wrong")
+}
+
+func TestScopeBuiltins(t *testing.T) {
+	builtins := DefaultBuiltins().Scope("len", "first", "missing")
+	assertions.AssertDeepEquals(t, []string{"first", "len"}, builtins.Names(), "builtins.Names() wrong")
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`puts("hello")`, "identifier not found: puts"},
+		{`push([], 1)`, "identifier not found: push"},
+		{`len(1, 2)`, "wrong number of arguments. got=2, want=1"},
+		{`first()`, "wrong number of arguments. got=0, want=1"},
+	}
+
+	for i, test := range tests {
+		evaluated := evalWithBuiltins(test.input, builtins)
+		assertions.AssertTypeOf(t, reflect.TypeOf(object.Error{}), evaluated, "test["+strconv.Itoa(i)+"] - unexpected type")
+		assertions.AssertStringEquals(t, test.expected, evaluated.(*object.Error).Message, "test["+strconv.Itoa(i)+"] - err.Message wrong")
+	}
+}
+
+func evalWithBuiltins(input string, builtins *Builtins) object.Object {
+	p := parser.New(input)
+	program := p.ParseProgram()
+	env := object.NewEnvironment()
+	e := New()
+	e.Builtins = builtins
+	return e.Eval(program, env)
+}
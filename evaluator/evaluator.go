@@ -1,6 +1,7 @@
 package evaluator
 
 import (
+	"context"
 	"fmt"
 	"github.com/digital-codex/monkey/ast"
 	"github.com/digital-codex/monkey/object"
@@ -24,6 +25,23 @@ var (
 	NULL  = &object.Null{}
 )
 
+type Evaluator struct {
+	Loader   *Loader
+	Builtins *Builtins
+	Limits   Limits
+	Profile  Profile
+	Optimize bool // optimize modules before evaluating them
+	Hygienic bool // rename the names bound by macro templates, see quote
+
+	ctx         context.Context
+	steps       int
+	depth       int
+	allocations int
+	allocated   int
+	expanding   int
+	macros      *object.Environment // the top-level macros, for the macroexpand builtin
+}
+
 type (
 	PrefixFunc func(object.Object) object.Object
 	InfixFunc  func(object.Object, object.Object) object.Object
@@ -114,46 +132,77 @@ func (io *InfixOperation) operation()  {}
  *                              PUBLIC FUNCTIONS                             *
  *****************************************************************************/
 
+func New() *Evaluator {
+	return &Evaluator{
+		Loader:   NewLoader(nil),
+		Builtins: DefaultBuiltins(),
+		Limits:   Limits{Depth: DEFAULT_MAX_DEPTH},
+		Profile:  FULL,
+	}
+}
+
 func Eval(node ast.Node, env *object.Environment) object.Object {
+	return New().Eval(node, env)
+}
+
+func (e *Evaluator) Apply(fn object.Object, args ...object.Object) object.Object {
+	return e.call(fn, args)
+}
+
+func (e *Evaluator) Eval(node ast.Node, env *object.Environment) object.Object {
+	if err := e.step(); err != nil {
+		return err
+	}
+
 	switch node := node.(type) {
 	case *ast.Program:
-		return evalProgram(node, env)
+		return e.evalProgram(node, env)
 	case *ast.LetDeclaration:
-		if err, ok := evalLetDeclaration(node, env); !ok {
+		if err, ok := e.evalLetDeclaration(node, env); !ok {
+			return err
+		}
+	case *ast.ImportStatement:
+		if err, ok := e.evalImportStatement(node, env); !ok {
+			return err
+		}
+	case *ast.ExportDeclaration:
+		if err, ok := e.evalLetDeclaration(node.Declaration, env); !ok {
 			return err
 		}
 	case *ast.ReturnStatement:
-		return evalReturnStatement(node, env)
+		return e.evalReturnStatement(node, env)
 	case *ast.ExpressionStatement:
-		return evalExpressionStatement(node, env)
+		return e.evalExpressionStatement(node, env)
 	case *ast.Block:
-		return evalBlock(node, env)
+		return e.evalBlock(node, env)
 	case *ast.Identifier:
-		return evalIdentifier(node, env)
+		return e.evalIdentifier(node, env)
 	case *ast.NumberLiteral:
 		return evalNumberLiteral(node)
 	case *ast.PrefixExpression:
-		return evalPrefixExpression(node, env)
+		return e.evalPrefixExpression(node, env)
 	case *ast.InfixExpression:
-		return evalInfixExpression(node, env)
+		return e.evalInfixExpression(node, env)
 	case *ast.GroupedExpression:
-		return evalGroupedExpression(node, env)
+		return e.evalGroupedExpression(node, env)
 	case *ast.Boolean:
 		return evalBoolean(node)
+	case *ast.Null:
+		return NULL
 	case *ast.IfExpression:
-		return evalIfExpression(node, env)
+		return e.evalIfExpression(node, env)
 	case *ast.FunctionLiteral:
 		return evalFunctionLiteral(node, env)
 	case *ast.CallExpression:
-		return evalCallExpression(node, env)
+		return e.evalCallExpression(node, env)
 	case *ast.StringLiteral:
-		return evalStringLiteral(node)
+		return e.allocate(evalStringLiteral(node))
 	case *ast.ArrayLiteral:
-		return evalArrayLiteral(node, env)
+		return e.evalArrayLiteral(node, env)
 	case *ast.IndexExpression:
-		return evalIndexExpression(node, env)
+		return e.evalIndexExpression(node, env)
 	case *ast.HashLiteral:
-		return evalHashLiteral(node, env)
+		return e.evalHashLiteral(node, env)
 	}
 
 	return nil
@@ -163,11 +212,11 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
  *                             PRIVATE FUNCTIONS                             *
  *****************************************************************************/
 
-func evalProgram(node *ast.Program, env *object.Environment) object.Object {
+func (e *Evaluator) evalProgram(node *ast.Program, env *object.Environment) object.Object {
 	var result object.Object
 
 	for _, statement := range node.Statements {
-		result = Eval(statement, env)
+		result = e.Eval(statement, env)
 
 		switch result := result.(type) {
 		case *object.ReturnValue:
@@ -180,32 +229,45 @@ func evalProgram(node *ast.Program, env *object.Environment) object.Object {
 	return result
 }
 
-func evalLetDeclaration(node *ast.LetDeclaration, env *object.Environment) (object.Object, bool) {
-	val := Eval(node.Value, env)
+func (e *Evaluator) evalLetDeclaration(node *ast.LetDeclaration, env *object.Environment) (object.Object, bool) {
+	val := e.Eval(node.Value, env)
 	if isError(val) {
 		return val, false
 	}
-	env.Set(node.Name.Value, val)
+	if node.Name.Scope == ast.LOCAL {
+		env.SetAt(node.Name.Depth, node.Name.Slot, val)
+	} else {
+		env.Set(node.Name.Value, val)
+	}
+	return nil, true
+}
+
+func (e *Evaluator) evalImportStatement(node *ast.ImportStatement, env *object.Environment) (object.Object, bool) {
+	module := e.importModule(node.Path.Value)
+	if isError(module) {
+		return module, false
+	}
+	env.Set(node.Name.Value, module)
 	return nil, true
 }
 
-func evalReturnStatement(node *ast.ReturnStatement, env *object.Environment) object.Object {
-	val := Eval(node.ReturnValue, env)
+func (e *Evaluator) evalReturnStatement(node *ast.ReturnStatement, env *object.Environment) object.Object {
+	val := e.Eval(node.ReturnValue, env)
 	if isError(val) {
 		return val
 	}
 	return &object.ReturnValue{Value: val}
 }
 
-func evalExpressionStatement(node *ast.ExpressionStatement, env *object.Environment) object.Object {
-	return Eval(node.Expression, env)
+func (e *Evaluator) evalExpressionStatement(node *ast.ExpressionStatement, env *object.Environment) object.Object {
+	return e.Eval(node.Expression, env)
 }
 
-func evalBlock(node *ast.Block, env *object.Environment) object.Object {
+func (e *Evaluator) evalBlock(node *ast.Block, env *object.Environment) object.Object {
 	var result object.Object
 
 	for _, statement := range node.Statements {
-		result = Eval(statement, env)
+		result = e.Eval(statement, env)
 
 		if result != nil {
 			rt := result.Type()
@@ -218,12 +280,33 @@ func evalBlock(node *ast.Block, env *object.Environment) object.Object {
 	return result
 }
 
-func evalIdentifier(node *ast.Identifier, env *object.Environment) object.Object {
-	if val, ok := env.Get(node.Value); ok {
-		return val
+func (e *Evaluator) evalIdentifier(node *ast.Identifier, env *object.Environment) object.Object {
+	switch node.Scope {
+	case ast.LOCAL:
+		if val, ok := env.GetAt(node.Depth, node.Slot); ok {
+			return val
+		}
+		// the slot is bound by a later let of the function, so fall back to
+		// outer scopes; parameter slots are always bound by call
+		if val, ok := env.Get(node.Value); ok {
+			return val
+		}
+	case ast.GLOBAL:
+		if val, ok := env.Global().Get(node.Value); ok {
+			return val
+		}
+	default:
+		if val, ok := env.Get(node.Value); ok {
+			return val
+		}
 	}
-	if builtin, ok := builtins[node.Value]; ok {
-		return builtin
+	if e.Builtins != nil {
+		if builtin, ok := e.Builtins.Get(node.Value); ok {
+			if err := e.checkBuiltin(node.Value); err != nil {
+				return err
+			}
+			return builtin
+		}
 	}
 
 	return makeError("identifier not found: " + node.Value)
@@ -233,8 +316,8 @@ func evalNumberLiteral(node *ast.NumberLiteral) object.Object {
 	return &object.Number{Value: node.Value}
 }
 
-func evalPrefixExpression(node *ast.PrefixExpression, env *object.Environment) object.Object {
-	right := Eval(node.Right, env)
+func (e *Evaluator) evalPrefixExpression(node *ast.PrefixExpression, env *object.Environment) object.Object {
+	right := e.Eval(node.Right, env)
 	if isError(right) {
 		return right
 	}
@@ -255,13 +338,13 @@ func evalPrefixExpression(node *ast.PrefixExpression, env *object.Environment) o
 	return makeError("unknown operator: %s%s", node.Operator, right.Type())
 }
 
-func evalInfixExpression(node *ast.InfixExpression, env *object.Environment) object.Object {
-	left := Eval(node.Left, env)
+func (e *Evaluator) evalInfixExpression(node *ast.InfixExpression, env *object.Environment) object.Object {
+	left := e.Eval(node.Left, env)
 	if isError(left) {
 		return left
 	}
 
-	right := Eval(node.Right, env)
+	right := e.Eval(node.Right, env)
 	if isError(right) {
 		return right
 	}
@@ -279,71 +362,96 @@ func evalInfixExpression(node *ast.InfixExpression, env *object.Environment) obj
 		op, ok := op.(*InfixOperation)
 		if ok {
 			if (left.Type() == op.left || op.left == object.ANY) && (right.Type() == op.right || op.right == object.ANY) {
-				return op.apply(left, right)
+				return e.allocate(op.apply(left, right))
 			}
 		}
 	}
 	return makeError("unknown operator: %s %s %s", left.Type(), node.Operator, right.Type())
 }
 
-func evalGroupedExpression(node *ast.GroupedExpression, env *object.Environment) object.Object {
-	return Eval(node.Expression, env)
+func (e *Evaluator) evalGroupedExpression(node *ast.GroupedExpression, env *object.Environment) object.Object {
+	return e.Eval(node.Expression, env)
 }
 
 func evalBoolean(node *ast.Boolean) object.Object {
 	return convertNativeBoolToBooleanObject(node.Value)
 }
 
-func evalIfExpression(node *ast.IfExpression, env *object.Environment) object.Object {
-	condition := Eval(node.Condition, env)
+func (e *Evaluator) evalIfExpression(node *ast.IfExpression, env *object.Environment) object.Object {
+	condition := e.Eval(node.Condition, env)
 	if isError(condition) {
 		return condition
 	}
 
 	if isTruthy(condition) {
-		return Eval(node.Consequence, env)
+		return e.Eval(node.Consequence, env)
 	} else if node.Alternative != nil {
-		return Eval(node.Alternative, env)
+		return e.Eval(node.Alternative, env)
 	}
 
 	return NULL
 }
 
 func evalFunctionLiteral(node *ast.FunctionLiteral, env *object.Environment) object.Object {
-	return &object.Function{Parameters: node.Parameters, Env: env, Body: node.Body}
+	return &object.Function{Parameters: node.Parameters, Env: env, Body: node.Body, Locals: node.Locals}
 }
 
-func evalCallExpression(node *ast.CallExpression, env *object.Environment) object.Object {
+func (e *Evaluator) evalCallExpression(node *ast.CallExpression, env *object.Environment) object.Object {
 	if isQuoteCall(node) {
-		return quote(node.Argument[0], env)
+		return e.quote(node.Argument[0], env)
 	}
 
-	fn := Eval(node.Function, env)
+	fn := e.Eval(node.Function, env)
 	if isError(fn) {
 		return fn
 	}
-	args := evalExpressions(node.Argument, env)
+	args := e.evalExpressions(node.Argument, env)
 	if len(args) == 1 && isError(args[0]) {
 		return args[0]
 	}
 
-	return call(fn, args)
+	return e.call(fn, args)
 }
 
-func call(fn object.Object, args []object.Object) object.Object {
-	switch fn := fn.(type) {
-	case *object.Function:
-		extendedEnv := object.ExtendEnvironment(fn, args)
-		evaluated := Eval(fn.Body, extendedEnv)
+// call applies fn to args. Calls in tail position of a function body come
+// back as object.TailCall and are performed by the loop, reusing this frame.
+func (e *Evaluator) call(fn object.Object, args []object.Object) object.Object {
+	if _, ok := fn.(*object.Function); ok {
+		if err := e.enter(); err != nil {
+			return err
+		}
+		defer e.leave()
+	}
+
+	for {
+		switch f := fn.(type) {
+		case *object.Function:
+			if len(args) != len(f.Parameters) {
+				return makeError("%s", arityError(len(args), len(f.Parameters)))
+			}
+			extendedEnv := object.ExtendEnvironment(f, args)
+			evaluated := e.evalTail(f.Body, extendedEnv)
 
-		if returnValue, ok := evaluated.(*object.ReturnValue); ok {
-			return returnValue.Value
+			if tailCall, ok := evaluated.(*object.TailCall); ok {
+				fn, args = tailCall.Function, tailCall.Arguments
+				continue
+			}
+			if returnValue, ok := evaluated.(*object.ReturnValue); ok {
+				return returnValue.Value
+			}
+			return evaluated
+		case *object.Builtin:
+			if f.Arity != object.VARIADIC && len(args) != f.Arity {
+				return makeError("%s", arityError(len(args), f.Arity))
+			}
+			result := f.Fn(args...)
+			if !f.Allocates {
+				return result
+			}
+			return e.allocate(result)
+		default:
+			return makeError("not a function: %s", fn.Type())
 		}
-		return evaluated
-	case *object.Builtin:
-		return fn.Fn(args...)
-	default:
-		return makeError("not a function: %s", fn.Type())
 	}
 }
 
@@ -351,21 +459,21 @@ func evalStringLiteral(node *ast.StringLiteral) object.Object {
 	return &object.String{Value: node.Value}
 }
 
-func evalArrayLiteral(node *ast.ArrayLiteral, env *object.Environment) object.Object {
-	elements := evalExpressions(node.Elements, env)
+func (e *Evaluator) evalArrayLiteral(node *ast.ArrayLiteral, env *object.Environment) object.Object {
+	elements := e.evalExpressions(node.Elements, env)
 	if len(elements) == 1 && isError(elements[0]) {
 		return elements[0]
 	}
 
-	return &object.Array{Elements: elements}
+	return e.allocate(&object.Array{Elements: elements})
 }
 
-func evalIndexExpression(node *ast.IndexExpression, env *object.Environment) object.Object {
-	left := Eval(node.Left, env)
+func (e *Evaluator) evalIndexExpression(node *ast.IndexExpression, env *object.Environment) object.Object {
+	left := e.Eval(node.Left, env)
 	if isError(left) {
 		return left
 	}
-	index := Eval(node.Index, env)
+	index := e.Eval(node.Index, env)
 	if isError(index) {
 		return index
 	}
@@ -394,16 +502,30 @@ func evalIndexExpression(node *ast.IndexExpression, env *object.Environment) obj
 		}
 
 		return pair.Value
+	case left.Type() == object.MODULE:
+		module := left.(*object.Module)
+
+		name, ok := index.(*object.String)
+		if !ok {
+			return makeError("unusable as export name: %s", index.Type())
+		}
+
+		export, ok := module.Exports[name.Value]
+		if !ok {
+			return makeError("%s is not exported by module %s", name.Value, module.Name)
+		}
+
+		return export
 	default:
 		return makeError("index operator not supported: %s", left.Type())
 	}
 }
 
-func evalHashLiteral(node *ast.HashLiteral, env *object.Environment) object.Object {
+func (e *Evaluator) evalHashLiteral(node *ast.HashLiteral, env *object.Environment) object.Object {
 	pairs := make(map[object.HashKey]object.HashPair)
 
 	for keyNode, valueNode := range node.Pairs {
-		key := Eval(keyNode, env)
+		key := e.Eval(keyNode, env)
 		if isError(key) {
 			return key
 		}
@@ -413,7 +535,7 @@ func evalHashLiteral(node *ast.HashLiteral, env *object.Environment) object.Obje
 			return makeError("unusable as hash key: %s", key.Type())
 		}
 
-		value := Eval(valueNode, env)
+		value := e.Eval(valueNode, env)
 		if isError(value) {
 			return value
 		}
@@ -422,14 +544,14 @@ func evalHashLiteral(node *ast.HashLiteral, env *object.Environment) object.Obje
 		pairs[hashed] = object.HashPair{Key: key, Value: value}
 	}
 
-	return &object.Hash{Pairs: pairs}
+	return e.allocate(&object.Hash{Pairs: pairs})
 }
 
-func evalExpressions(exprs []ast.Expression, env *object.Environment) []object.Object {
+func (e *Evaluator) evalExpressions(exprs []ast.Expression, env *object.Environment) []object.Object {
 	var result []object.Object
 
 	for _, expr := range exprs {
-		evaluated := Eval(expr, env)
+		evaluated := e.Eval(expr, env)
 		if isError(evaluated) {
 			return []object.Object{evaluated}
 		}
@@ -467,6 +589,12 @@ func convertNativeBoolToBooleanObject(b bool) object.Object {
 	return FALSE
 }
 
+// arityError is the message for a call with got arguments to a function,
+// macro or builtin taking want.
+func arityError(got int, want int) string {
+	return fmt.Sprintf("wrong number of arguments. got=%d, want=%d", got, want)
+}
+
 func makeError(format string, a ...any) *object.Error {
 	return &object.Error{Message: fmt.Sprintf(format, a...)}
 }
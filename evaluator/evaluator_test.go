@@ -4,6 +4,7 @@ import (
 	"github.com/digital-codex/assertions"
 	"github.com/digital-codex/monkey/object"
 	"github.com/digital-codex/monkey/parser"
+	"github.com/digital-codex/monkey/resolver"
 	"reflect"
 	"strconv"
 	"testing"
@@ -106,6 +107,8 @@ func TestEval(t *testing.T) {
 		{`{"foo": 5}["bar"]`, NULL},
 		{`let key = "foo"; {"foo": 5}[key]`, 5},
 		{`{}["foo"]`, NULL},
+		{`null`, NULL},
+		{`let f = fn() { null }; f() == null`, true},
 		{`{5: 5}[5]`, 5},
 		{`{true: 5}[true]`, 5},
 		{`{false: 5}[false]`, 5},
@@ -114,7 +117,40 @@ func TestEval(t *testing.T) {
 	for i, test := range tests {
 		evaluated := eval(test.input)
 		testObject(evaluated)(t, i, evaluated, test.expected)
+
+		resolved := evalResolved(t, test.input)
+		testObject(resolved)(t, i, resolved, test.expected)
+	}
+}
+
+func TestResolvedScopes(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected any
+	}{
+		{`let x = 1; let f = fn() { let y = x; let x = 2; y + x }; f()`, 3},
+		{`let f = fn() { let g = fn(n) { if (n == 0) { 0 } else { g(n - 1) } }; g(5) }; f()`, 0},
+		{`let f = fn() { let even = fn(n) { if (n == 0) { true } else { odd(n - 1) } }; let odd = fn(n) { if (n == 0) { false } else { even(n - 1) } }; odd(7) }; f()`, true},
+		{`let adder = fn(a) { fn(b) { fn(c) { a + b + c } } }; adder(1)(2)(3)`, 6},
+		{`let f = fn(a) { if (a > 0) { let b = a * 2; } else { let b = 0; } b }; f(2) + f(-1)`, 4},
+		{`let f = fn(x, x) { x }; f(1, 2)`, 2},
+		{`let f = fn(a) { let a = a + 1; a }; f(1)`, 2},
+		{`let counter = fn() { let n = 0; fn() { n } }; counter()()`, 0},
+		{`let f = fn() { g() }; let g = fn() { 7 }; f()`, 7},
+	}
+
+	for i, test := range tests {
+		evaluated := evalResolved(t, test.input)
+		testObject(evaluated)(t, i, evaluated, test.expected)
+	}
+
+	for i, input := range []string{`let x = 99; let f = fn(x) { x }; f()`, `let f = fn(x) { x }; f(1, 2)`} {
+		evaluated := evalResolved(t, input)
+		assertions.AssertTypeOf(t, reflect.TypeOf(object.Error{}), evaluated, "test["+strconv.Itoa(i)+"] - unexpected type")
 	}
+
+	quoted := evalResolved(t, `let f = fn(a) { quote(unquote(a) + b) }; f(1)`)
+	assertions.AssertStringEquals(t, "QUOTE((1.000000 + b))", quoted.Inspect(), "quoted.Inspect() wrong")
 }
 
 func TestErrorHandling(t *testing.T) {
@@ -135,6 +171,10 @@ func TestErrorHandling(t *testing.T) {
 		{`foobar`, "identifier not found: foobar"},
 		{`"Hello" - "World"`, "unknown operator: STRING - STRING"},
 		{`{"name": "Monkey"}[fn(x) { x}];`, "unusable as hash key: FUNCTION"},
+		{`let x = 99; let f = fn(x) { x }; f()`, "wrong number of arguments. got=0, want=1"},
+		{`fn(x) { x }()`, "wrong number of arguments. got=0, want=1"},
+		{`fn(x) { x }(1, 2)`, "wrong number of arguments. got=2, want=1"},
+		{`let f = fn(n) { g(n) }; let g = fn(a, b) { a }; f(1)`, "wrong number of arguments. got=1, want=2"},
 	}
 
 	for i, test := range tests {
@@ -151,6 +191,18 @@ func eval(input string) object.Object {
 	return Eval(program, env)
 }
 
+func evalResolved(t *testing.T, input string) object.Object {
+	p := parser.New(input)
+	program := p.ParseProgram()
+	r := resolver.New(New().isBuiltin)
+	r.Resolve(program)
+	if len(r.Errors()) != 0 {
+		t.Fatalf("resolver has %d errors: %v", len(r.Errors()), r.Errors())
+	}
+	env := object.NewEnvironment()
+	return Eval(program, env)
+}
+
 func testObject(o object.Object) func(*testing.T, int, object.Object, any) {
 	switch o.Type() {
 	case object.NUMBER:
@@ -210,3 +262,27 @@ func testStringObject(t *testing.T, i int, o object.Object, expected any) {
 	}
 	assertions.AssertStringEquals(t, value, o.(*object.String).Value, "test["+strconv.Itoa(i)+"] - o.(*object.String).Value wrong")
 }
+
+func BenchmarkFib(b *testing.B) {
+	input := `let fib = fn(n) { if (n < 2) { n } else { fib(n - 1) + fib(n - 2) } }; fib(20)`
+	tests := []struct {
+		name    string
+		resolve bool
+	}{
+		{"names", false},
+		{"slots", true},
+	}
+
+	for _, test := range tests {
+		b.Run(test.name, func(b *testing.B) {
+			program := parser.New(input).ParseProgram()
+			if test.resolve {
+				resolver.New(New().isBuiltin).Resolve(program)
+			}
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				Eval(program, object.NewEnvironment())
+			}
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package evaluator
+
+import (
+	"github.com/digital-codex/monkey/ast"
+	"github.com/digital-codex/monkey/object"
+	"strconv"
+	"sync/atomic"
+)
+
+// symbols counts the symbols made so far, so that no two of them share a
+// name.
+var symbols atomic.Int64
+
+/*****************************************************************************
+ *                             PRIVATE FUNCTIONS                             *
+ *****************************************************************************/
+
+// newSymbol returns a fresh symbol named after prefix. The
+// ast.SYMBOL_SEPARATOR in its name cannot appear in an identifier of the
+// source code, so the symbol neither captures nor is captured by a name the
+// user wrote.
+func newSymbol(prefix string) *object.Symbol {
+	return &object.Symbol{Name: prefix + ast.SYMBOL_SEPARATOR + strconv.FormatInt(symbols.Add(1), 10)}
+}
+
+// templateNodes returns the nodes written in the template of a quote, that is
+// every node of quoted but the calls to unquote and unquote_splice and the
+// unquoted nodes inside them.
+func templateNodes(quoted ast.Node, unquoted map[ast.Node]bool) map[ast.Node]bool {
+	template := make(map[ast.Node]bool)
+	ast.Modify(quoted, func(node ast.Node) ast.Node {
+		if !unquoted[node] && !isUnquotedCall(node) && !isSpliceCall(node) {
+			template[node] = true
+		}
+		return node
+	})
+
+	return template
+}
+
+// rename gives the identifiers of the template their final names. Those bound
+// to a symbol in env take the name of the symbol. While expanding a macro in
+// hygienic mode, the names bound by the template itself are renamed to fresh
+// symbols, so they cannot capture the names of the macro arguments nor be
+// seen by the code around the macro call.
+func (e *Evaluator) rename(quoted ast.Node, template map[ast.Node]bool, env *object.Environment) {
+	renamed := make(map[string]string)
+	if e.Hygienic && e.expanding > 0 {
+		for name := range boundNames(quoted, template) {
+			renamed[name] = newSymbol(name).Name
+		}
+	}
+
+	apply := func(ident *ast.Identifier) {
+		if obj, ok := env.Get(ident.Value); ok {
+			if symbol, ok := obj.(*object.Symbol); ok {
+				ident.Value = symbol.Name
+				ident.Token.Lexeme = symbol.Name
+				return
+			}
+		}
+		if name, ok := renamed[ident.Value]; ok {
+			ident.Value = name
+			ident.Token.Lexeme = name
+		}
+	}
+
+	ast.Modify(quoted, func(node ast.Node) ast.Node {
+		if !template[node] {
+			return node
+		}
+		switch node := node.(type) {
+		case *ast.Identifier:
+			apply(node)
+		case *ast.LetDeclaration:
+			apply(node.Name)
+		}
+		return node
+	})
+}
+
+// boundNames returns the names declared by let and by function parameters in
+// the template.
+func boundNames(quoted ast.Node, template map[ast.Node]bool) map[string]bool {
+	names := make(map[string]bool)
+	ast.Modify(quoted, func(node ast.Node) ast.Node {
+		if !template[node] {
+			return node
+		}
+		switch node := node.(type) {
+		case *ast.LetDeclaration:
+			names[node.Name.Value] = true
+		case *ast.FunctionLiteral:
+			for _, param := range node.Parameters {
+				names[param.Value] = true
+			}
+		}
+		return node
+	})
+
+	return names
+}
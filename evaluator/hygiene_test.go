@@ -0,0 +1,70 @@
+package evaluator
+
+import (
+	"github.com/digital-codex/assertions"
+	"github.com/digital-codex/monkey/object"
+	"reflect"
+	"regexp"
+	"strconv"
+	"testing"
+)
+
+func TestGensym(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`gensym()`, `g#N`},
+		{`gensym("tmp")`, `tmp#N`},
+		{`let t = gensym("t"); quote(fn(t) { t + 1 })`, `QUOTE(fn(t_N){(t_N + 1)})`},
+		{`let t = gensym("t"); quote(fn() { let t = 1; unquote(t) })`, `QUOTE(fn(){let t_N = 1;t_N})`},
+	}
+
+	counter := regexp.MustCompile(`([#_])\d+`)
+	for i, test := range tests {
+		evaluated := eval(test.input)
+		assertions.AssertStringEquals(t, test.expected, counter.ReplaceAllString(evaluated.Inspect(), "${1}N"), "test["+strconv.Itoa(i)+"] - evaluated.Inspect() wrong")
+	}
+
+	evaluated := eval(`[gensym("tmp"), gensym("tmp")]`).(*object.Array)
+	assertions.AssertTypeOf(t, reflect.TypeOf(object.Symbol{}), evaluated.Elements[0], "gensym() type wrong")
+	assertions.AssertBoolEquals(t, true, evaluated.Elements[0].Inspect() != evaluated.Elements[1].Inspect(), "gensym() should not repeat a name")
+}
+
+func TestGensymErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`gensym(1)`, "argument to `gensym` not supported, got NUMBER"},
+		{`gensym("a", "b")`, "wrong number of arguments. got=2, want=0 or 1"},
+	}
+
+	for i, test := range tests {
+		evaluated := eval(test.input)
+		assertions.AssertTypeOf(t, reflect.TypeOf(object.Error{}), evaluated, "test["+strconv.Itoa(i)+"] - unexpected type")
+		assertions.AssertStringEquals(t, test.expected, evaluated.(*object.Error).Message, "test["+strconv.Itoa(i)+"] - err.Message wrong")
+	}
+}
+
+func TestHygienicMacros(t *testing.T) {
+	tests := []struct {
+		input    string
+		hygienic bool
+		expected any
+	}{
+		{`let m = macro(x) { quote(unquote(x) + 1) }; m(1) + m(2)`, false, 5.0},
+		{`let m = macro(a, b) { quote(fn() { let tmp = unquote(a); tmp + unquote(b) }()) }; let tmp = 10; m(1, tmp)`, false, 2.0},
+		{`let m = macro(a, b) { quote(fn() { let tmp = unquote(a); tmp + unquote(b) }()) }; let tmp = 10; m(1, tmp)`, true, 11.0},
+		{`let m = macro(body) { quote(fn(x) { unquote(body) }(1)) }; let x = 5; m(x)`, false, 1.0},
+		{`let m = macro(body) { quote(fn(x) { unquote(body) }(1)) }; let x = 5; m(x)`, true, 5.0},
+		{`let m = macro(a, b) { let t = gensym("tmp"); quote(fn() { let t = unquote(a); t + unquote(b) }()) }; let tmp = 10; m(1, tmp)`, false, 11.0},
+		{`let double = fn(x) { x * 2 }; let m = macro(a) { quote(double(unquote(a))) }; m(4)`, true, 8.0},
+		{`let m = macro(x) { quote(if (true) { let y = unquote(x); y }) }; m(3) + m(4)`, true, 7.0},
+	}
+
+	for i, test := range tests {
+		evaluated := evalExpanded(test.input, test.hygienic)
+		testObject(evaluated)(t, i, evaluated, test.expected)
+	}
+}
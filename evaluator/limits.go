@@ -0,0 +1,99 @@
+package evaluator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/digital-codex/monkey/object"
+)
+
+/*****************************************************************************
+ *                                  TYPES                                    *
+ *****************************************************************************/
+
+const (
+	// DEFAULT_MAX_DEPTH keeps runaway recursion well clear of the Go stack
+	// limit.
+	DEFAULT_MAX_DEPTH = 10000
+
+	cancelCheckInterval = 256 // steps between polls of the context
+)
+
+var (
+	ErrCanceled    = errors.New("evaluation canceled")
+	ErrStepLimit   = errors.New("step limit exceeded")
+	ErrCallDepth   = errors.New("maximum call depth exceeded")
+	ErrMemoryLimit = errors.New("memory limit exceeded")
+)
+
+// Limits bounds the work a single run may do. A zero field means unlimited.
+type Limits struct {
+	Steps  int // nodes evaluated
+	Depth  int // nested function calls
+	Memory int // approximate bytes allocated for strings, arrays and hashes
+}
+
+// Stats describes the work done by a run.
+type Stats struct {
+	Steps       int
+	Allocations int
+	Allocated   int // approximate bytes, counting every allocation once
+}
+
+/*****************************************************************************
+ *                              PUBLIC FUNCTIONS                             *
+ *****************************************************************************/
+
+// Reset starts a new run governed by ctx: the step and allocation counts
+// drop back to zero and evaluation stops with ErrCanceled once ctx is done.
+func (e *Evaluator) Reset(ctx context.Context) {
+	e.ctx = ctx
+	e.steps = 0
+	e.depth = 0
+	e.allocations = 0
+	e.allocated = 0
+	e.expanding = 0
+}
+
+// Stats returns the work done since the last Reset.
+func (e *Evaluator) Stats() Stats {
+	return Stats{Steps: e.steps, Allocations: e.allocations, Allocated: e.allocated}
+}
+
+/*****************************************************************************
+ *                             PRIVATE FUNCTIONS                             *
+ *****************************************************************************/
+
+func (e *Evaluator) step() *object.Error {
+	e.steps++
+
+	if e.Limits.Steps > 0 && e.steps > e.Limits.Steps {
+		return abort(fmt.Errorf("%w: %d", ErrStepLimit, e.Limits.Steps))
+	}
+
+	if e.ctx != nil && e.steps%cancelCheckInterval == 0 {
+		if err := e.ctx.Err(); err != nil {
+			return abort(fmt.Errorf("%w: %w", ErrCanceled, err))
+		}
+	}
+
+	return nil
+}
+
+func (e *Evaluator) enter() *object.Error {
+	e.depth++
+
+	if e.Limits.Depth > 0 && e.depth > e.Limits.Depth {
+		return abort(fmt.Errorf("%w: %d", ErrCallDepth, e.Limits.Depth))
+	}
+
+	return nil
+}
+
+func (e *Evaluator) leave() {
+	e.depth--
+}
+
+func abort(err error) *object.Error {
+	return &object.Error{Message: err.Error(), Cause: err}
+}
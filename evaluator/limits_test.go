@@ -0,0 +1,62 @@
+package evaluator
+
+import (
+	"context"
+	"errors"
+	"github.com/digital-codex/assertions"
+	"github.com/digital-codex/monkey/object"
+	"github.com/digital-codex/monkey/parser"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func TestLimits(t *testing.T) {
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		input    string
+		ctx      context.Context
+		limits   Limits
+		expected error
+		message  string
+	}{
+		{`let f = fn() { 1 + f() }; f()`, nil, Limits{Depth: DEFAULT_MAX_DEPTH}, ErrCallDepth, "maximum call depth exceeded: 10000"},
+		{`let f = fn(n) { if (n > 0) { 1 + f(n - 1) } else { 0 } }; f(20)`, nil, Limits{Depth: 10}, ErrCallDepth, "maximum call depth exceeded: 10"},
+		{`let f = fn(n) { if (n > 0) { f(n - 1) } else { 0 } }; f(1000)`, nil, Limits{Steps: 100}, ErrStepLimit, "step limit exceeded: 100"},
+		{`let f = fn(n) { if (n > 0) { f(n - 1) } else { 0 } }; f(1000)`, canceled, Limits{}, ErrCanceled, "evaluation canceled: context canceled"},
+	}
+
+	for i, test := range tests {
+		evaluated := evalWithLimits(test.ctx, test.input, test.limits)
+		assertions.AssertTypeOf(t, reflect.TypeOf(object.Error{}), evaluated, "test["+strconv.Itoa(i)+"] - unexpected type")
+		err := evaluated.(*object.Error)
+		assertions.AssertStringEquals(t, test.message, err.Message, "test["+strconv.Itoa(i)+"] - err.Message wrong")
+		assertions.AssertBoolEquals(t, true, errors.Is(err.Cause, test.expected), "test["+strconv.Itoa(i)+"] - err.Cause wrong")
+	}
+}
+
+func TestWithinLimits(t *testing.T) {
+	input := `let f = fn(n) { if (n > 0) { 1 + f(n - 1) } else { 0 } }; f(50)`
+
+	e := New()
+	e.Limits = Limits{Steps: 10000, Depth: 51}
+	e.Reset(context.Background())
+	evaluated := e.Eval(parser.New(input).ParseProgram(), object.NewEnvironment())
+	testObject(evaluated)(t, 0, evaluated, 50)
+
+	assertions.AssertBoolEquals(t, true, e.Stats().Steps > 0 && e.Stats().Steps <= 10000, "e.Stats().Steps wrong")
+	e.Reset(context.Background())
+	assertions.AssertIntEquals(t, 0, e.Stats().Steps, "e.Stats().Steps not reset")
+}
+
+func evalWithLimits(ctx context.Context, input string, limits Limits) object.Object {
+	p := parser.New(input)
+	program := p.ParseProgram()
+	env := object.NewEnvironment()
+	e := New()
+	e.Limits = limits
+	e.Reset(ctx)
+	return e.Eval(program, env)
+}
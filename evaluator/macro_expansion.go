@@ -1,69 +1,246 @@
 package evaluator
 
 import (
+	"fmt"
 	"github.com/digital-codex/monkey/ast"
 	"github.com/digital-codex/monkey/object"
 )
 
+/*****************************************************************************
+ *                                  TYPES                                    *
+ *****************************************************************************/
+
+// MAX_EXPANSION_DEPTH bounds how many times the code returned by a macro is
+// expanded again, so that a macro expanding to a call of itself fails instead
+// of looping forever.
+const MAX_EXPANSION_DEPTH = 100
+
+type expander struct {
+	e      *Evaluator
+	limit  int // levels of expansion to perform, 0 for no limit
+	errors []error
+}
+
 /*****************************************************************************
  *                              PUBLIC FUNCTIONS                             *
  *****************************************************************************/
 
+// DefineMacros binds the macros declared by the top-level statements of
+// program in env and removes their declarations from program.
 func DefineMacros(program *ast.Program, env *object.Environment) {
-	var definitions []int
-	for i, stmt := range program.Statements {
-		if isMacroDefinition(stmt) {
-			decl, _ := stmt.(*ast.LetDeclaration)
-			lit, _ := decl.Value.(*ast.MacroLiteral)
-
-			macro := &object.Macro{
-				Parameters: lit.Parameters,
-				Env:        env,
-				Body:       lit.Body,
-			}
+	program.Statements = defineMacros(program.Statements, env)
+}
 
-			env.Set(decl.Name.Value, macro)
-			definitions = append(definitions, i)
-		}
-	}
+func ExpandMacros(program ast.Node, env *object.Environment) (ast.Node, []error) {
+	return New().ExpandMacros(program, env)
+}
 
-	for i := len(definitions) - 1; i >= 0; i-- {
-		idx := definitions[i]
-		program.Statements = append(program.Statements[:idx], program.Statements[idx+1:]...)
+// ExpandMacros replaces the calls to macros with the code they return, which
+// is expanded in turn until no macro call is left. Outer calls are expanded
+// first, so a macro receives its arguments unexpanded, and the code quoted by
+// quote is only expanded where it is unquoted. Top-level macros are
+// looked up in env, and each block may declare macros of its own, visible
+// everywhere inside the block. A call that cannot be expanded is left in
+// place and reported in the returned errors, which carry the position of the
+// call.
+func (e *Evaluator) ExpandMacros(program ast.Node, env *object.Environment) (ast.Node, []error) {
+	return e.ExpandMacrosDepth(program, env, 0)
+}
+
+// ExpandMacrosDepth is like ExpandMacros but performs at most depth levels of
+// expansion: with a depth of 1 only the macro calls written in program are
+// expanded, and the calls in the code they return are left in place. A depth
+// of 0 means no limit. The macros of env are also the ones the macroexpand
+// builtin sees when program runs.
+func (e *Evaluator) ExpandMacrosDepth(program ast.Node, env *object.Environment, depth int) (ast.Node, []error) {
+	e.macros = env
+	if e.Builtins != nil {
+		e.Builtins.expander.e = e
 	}
+	x := &expander{e: e, limit: depth}
+	expanded := x.expand(program, env, 0)
+	return expanded, x.errors
 }
 
-func ExpandMacros(program ast.Node, env *object.Environment) ast.Node {
-	return ast.Modify(program, func(node ast.Node) ast.Node {
-		ce, ok := node.(*ast.CallExpression)
-		if !ok {
-			return node
-		}
+/*****************************************************************************
+ *                             PRIVATE FUNCTIONS                             *
+ *****************************************************************************/
 
-		macro, ok := isMacroCall(ce, env)
-		if !ok {
+// expand expands the macro calls in node, where depth counts the expansions
+// that produced node.
+func (x *expander) expand(node ast.Node, env *object.Environment, depth int) ast.Node {
+	switch node := node.(type) {
+	case *ast.Program:
+		x.expandStatements(node.Statements, env, depth)
+	case *ast.Block:
+		scope := object.NewEnclosedEnvironment(env)
+		node.Statements = defineMacros(node.Statements, scope)
+		x.expandStatements(node.Statements, scope, depth)
+	case *ast.LetDeclaration:
+		node.Value = x.expandExpression(node.Value, env, depth)
+	case *ast.ExportDeclaration:
+		x.expand(node.Declaration, env, depth)
+	case *ast.ReturnStatement:
+		node.ReturnValue = x.expandExpression(node.ReturnValue, env, depth)
+	case *ast.ExpressionStatement:
+		node.Expression = x.expandExpression(node.Expression, env, depth)
+	case *ast.PrefixExpression:
+		node.Right = x.expandExpression(node.Right, env, depth)
+	case *ast.InfixExpression:
+		node.Left = x.expandExpression(node.Left, env, depth)
+		node.Right = x.expandExpression(node.Right, env, depth)
+	case *ast.GroupedExpression:
+		node.Expression = x.expandExpression(node.Expression, env, depth)
+	case *ast.IfExpression:
+		node.Condition = x.expandExpression(node.Condition, env, depth)
+		x.expand(node.Consequence, env, depth)
+		if node.Alternative != nil {
+			x.expand(node.Alternative, env, depth)
+		}
+	case *ast.FunctionLiteral:
+		x.expand(node.Body, env, depth)
+	case *ast.CallExpression:
+		if macro, ok := isMacroCall(node, env); ok {
+			return x.expandCall(node, macro, env, depth)
+		}
+		if isQuoteCall(node) {
+			// quoted code is expanded once a macro returns it or it is passed
+			// to macroexpand; only the code it unquotes runs here
+			x.expandUnquoted(node, env, depth)
 			return node
 		}
-
-		var args []object.Object
-		for _, arg := range ce.Argument {
-			args = append(args, &object.Quote{Node: arg})
+		node.Function = x.expandExpression(node.Function, env, depth)
+		x.expandExpressions(node.Argument, env, depth)
+	case *ast.ArrayLiteral:
+		x.expandExpressions(node.Elements, env, depth)
+	case *ast.IndexExpression:
+		node.Left = x.expandExpression(node.Left, env, depth)
+		node.Index = x.expandExpression(node.Index, env, depth)
+	case *ast.HashLiteral:
+		pairs := make(map[ast.Expression]ast.Expression, len(node.Pairs))
+		for key, val := range node.Pairs {
+			pairs[x.expandExpression(key, env, depth)] = x.expandExpression(val, env, depth)
 		}
-		extendedEnv := object.ExtendEnvironment(macro, args)
-		evaluated := Eval(macro.Body, extendedEnv)
+		node.Pairs = pairs
+	}
+
+	return node
+}
+
+func (x *expander) expandStatements(stmts []ast.Statement, env *object.Environment, depth int) {
+	for i, stmt := range stmts {
+		stmts[i] = x.expand(stmt, env, depth).(ast.Statement)
+	}
+}
+
+func (x *expander) expandExpression(node ast.Expression, env *object.Environment, depth int) ast.Expression {
+	if node == nil {
+		return nil
+	}
+	return x.expand(node, env, depth).(ast.Expression)
+}
 
-		q, ok := evaluated.(*object.Quote)
-		if !ok {
-			panic("we only support returning AST-nodes from macros")
+func (x *expander) expandExpressions(nodes []ast.Expression, env *object.Environment, depth int) {
+	for i, node := range nodes {
+		nodes[i] = x.expandExpression(node, env, depth)
+	}
+}
+
+// expandUnquoted expands the arguments of the unquote and unquote_splice calls
+// inside the quote call ce.
+func (x *expander) expandUnquoted(ce *ast.CallExpression, env *object.Environment, depth int) {
+	for _, arg := range ce.Argument {
+		ast.Inspect(arg, func(node ast.Node) bool {
+			if !isUnquotedCall(node) && !isSpliceCall(node) {
+				return true
+			}
+			x.expandExpressions(node.(*ast.CallExpression).Argument, env, depth)
+			return false
+		})
+	}
+}
+
+// expandCall replaces the call ce to macro with the expansion of the code the
+// macro returns.
+func (x *expander) expandCall(ce *ast.CallExpression, macro *object.Macro, env *object.Environment, depth int) ast.Node {
+	ident := ce.Function.(*ast.Identifier)
+	if x.limit > 0 && depth == x.limit {
+		return ce
+	}
+	if depth == MAX_EXPANSION_DEPTH {
+		x.fail(ident, fmt.Errorf("expanding macro %q exceeds the depth limit of %d", ident.Value, MAX_EXPANSION_DEPTH))
+		return ce
+	}
+
+	quote, err := x.e.expandMacro(ident.Value, macro, ce.Argument)
+	if err != nil {
+		x.fail(ident, err)
+		return ce
+	}
+
+	return x.expand(quote.Node, env, depth+1)
+}
+
+func (x *expander) fail(ident *ast.Identifier, err error) {
+	x.errors = append(x.errors, fmt.Errorf("Error:%d:%d: %w", ident.Token.Line, ident.Token.Start, err))
+}
+
+// defineMacros binds the macros declared by stmts in env and returns the other
+// statements.
+func defineMacros(stmts []ast.Statement, env *object.Environment) []ast.Statement {
+	var rest []ast.Statement
+	for _, stmt := range stmts {
+		if !isMacroDefinition(stmt) {
+			rest = append(rest, stmt)
+			continue
 		}
 
-		return q.Node
-	})
+		decl, _ := stmt.(*ast.LetDeclaration)
+		lit, _ := decl.Value.(*ast.MacroLiteral)
+		env.Set(decl.Name.Value, &object.Macro{
+			Parameters: lit.Parameters,
+			Env:        env,
+			Body:       lit.Body,
+		})
+	}
+
+	return rest
 }
 
-/*****************************************************************************
- *                             PRIVATE FUNCTIONS                             *
- *****************************************************************************/
+func (e *Evaluator) expandMacro(name string, macro *object.Macro, arguments []ast.Expression) (*object.Quote, error) {
+	if len(arguments) != len(macro.Parameters) {
+		return nil, fmt.Errorf("error in macro %q: %s", name, arityError(len(arguments), len(macro.Parameters)))
+	}
+
+	var args []object.Object
+	for _, arg := range arguments {
+		args = append(args, &object.Quote{Node: arg})
+	}
+	extendedEnv := object.ExtendEnvironment(macro, args)
+	e.expanding++
+	evaluated := e.Eval(macro.Body, extendedEnv)
+	e.expanding--
+	if returnValue, ok := evaluated.(*object.ReturnValue); ok {
+		evaluated = returnValue.Value
+	}
+
+	switch evaluated := evaluated.(type) {
+	case *object.Quote:
+		if _, ok := evaluated.Node.(ast.Expression); !ok {
+			return nil, fmt.Errorf("macro %q must return an expression, got a %s node", name, ast.Kind(evaluated.Node))
+		}
+		return evaluated, nil
+	case *object.Error:
+		if evaluated.Cause != nil {
+			return nil, fmt.Errorf("error in macro %q: %w", name, evaluated.Cause)
+		}
+		return nil, fmt.Errorf("error in macro %q: %s", name, evaluated.Message)
+	case nil:
+		return nil, fmt.Errorf("macro %q must return a quote, got nothing", name)
+	default:
+		return nil, fmt.Errorf("macro %q must return a quote, got %s", name, evaluated.Type())
+	}
+}
 
 func isMacroDefinition(node ast.Statement) bool {
 	decl, ok := node.(*ast.LetDeclaration)
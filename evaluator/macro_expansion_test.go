@@ -90,6 +90,13 @@ func TestExpandMacros(t *testing.T) {
 		{`let infix = macro() { quote(1 + 2); }; infix()`, `(1 + 2)`},
 		{`let reverse = macro(a, b) { quote(unquote(b) - unquote(a)); }; reverse(2 + 2, 10 - 5)`, `(10 - 5) - (2 + 2)`},
 		{`let unless = macro(condition, consequence, alternative) { quote(if (!(unquote(condition))) { unquote(consequence); } else { unquote(alternative); }); }; unless(10 > 5, puts("not greater"), puts("greater"));`, `if (!(10 > 5)) { puts("not greater") } else { puts("greater") }`},
+		{`let f = fn() { let twice = macro(x) { quote(unquote(x) * 2) }; twice(3) }; f()`, `let f = fn() { 3 * 2 }; f()`},
+		{`if (true) { let m = macro() { quote(1) }; m() }; m()`, `if (true) { 1 }; m()`},
+		{`let m = macro() { quote(1) }; if (true) { let m = macro() { quote(2) }; m() }; m()`, `if (true) { 2 }; 1`},
+		{`let one = macro() { quote(1) }; let inc = macro(x) { quote(unquote(x) + one()) }; inc(one())`, `1 + 1`},
+		{`let call = macro(f, a, b) { quote(unquote(f)(unquote_splice([a, b]))) }; call(max, 1, x)`, `max(1, x)`},
+		{`let swap = macro(call) { let parts = ast_children(call); ast_make_call(parts[0], [parts[2], parts[1]]) }; swap(minus(1, 2))`, `minus(2, 1)`},
+		{`let define = macro() { quote(if (true) { let seven = macro() { quote(7) }; seven() }) }; define()`, `if (true) { 7 }`},
 	}
 
 	for i, test := range tests {
@@ -98,13 +105,101 @@ func TestExpandMacros(t *testing.T) {
 		program := parse(test.input)
 		env := object.NewEnvironment()
 		DefineMacros(program, env)
-		actual := ExpandMacros(program, env)
+		actual, errs := ExpandMacros(program, env)
 
+		assertions.AssertIntEquals(t, 0, len(errs), "test["+strconv.Itoa(i)+"] - len(errs) wrong")
 		assertions.AssertStringEquals(t, expected.String(), actual.String(), "test["+strconv.Itoa(i)+"] - expanded.String() wrong")
 	}
 }
 
+func TestExpandMacrosErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{`let m = macro(x) { "code" }; m(1)`, []string{`Error:1:29: macro "m" must return a quote, got STRING`}},
+		{"let m = macro(x) { 1 + 1 };\n\nm(1)", []string{`Error:3:29: macro "m" must return a quote, got NUMBER`}},
+		{`let m = macro(x) { let y = 1; }; m(1)`, []string{`Error:1:33: macro "m" must return a quote, got nothing`}},
+		{`let m = macro(a, b) { quote(unquote(a)) }; m(1); m(1, 2, 3)`, []string{
+			`Error:1:43: error in macro "m": wrong number of arguments. got=1, want=2`,
+			`Error:1:49: error in macro "m": wrong number of arguments. got=3, want=2`,
+		}},
+		{`let m = macro() { quote(1) }; m(1)`, []string{`Error:1:30: error in macro "m": wrong number of arguments. got=1, want=0`}},
+		{`let m = macro(x) { 1 + true }; m(1)`, []string{`Error:1:31: error in macro "m": type mismatch: NUMBER + BOOLEAN`}},
+		{`let m = macro() { quote(unquote(fn() { 1 })) }; m()`, []string{`Error:1:48: error in macro "m": cannot unquote FUNCTION`}},
+		{`let m = macro() { last(ast_children(quote(fn() { 1 }))) }; m()`, []string{`Error:1:59: macro "m" must return an expression, got a block node`}},
+		{`let loop = macro() { quote(loop()) }; loop()`, []string{`Error:1:27: expanding macro "loop" exceeds the depth limit of 100`}},
+		{`let m = macro(x) { quote(unquote(missing)) }; m(1) + m(2)`, []string{
+			`Error:1:46: error in macro "m": identifier not found: missing`,
+			`Error:1:53: error in macro "m": identifier not found: missing`,
+		}},
+	}
+
+	for i, test := range tests {
+		program := parse(test.input)
+		env := object.NewEnvironment()
+		DefineMacros(program, env)
+		_, errs := ExpandMacros(program, env)
+
+		var actual []string
+		for _, err := range errs {
+			actual = append(actual, err.Error())
+		}
+		assertions.AssertDeepEquals(t, test.expected, actual, "test["+strconv.Itoa(i)+"] - errs wrong")
+	}
+}
+
+func TestMacroexpand(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`let inc = macro(x) { quote(unquote(x) + 1) }; macroexpand(quote(inc(2) * 3))`, `QUOTE(((2 + 1) * 3))`},
+		{`let one = macro() { quote(1) }; let inc = macro(x) { quote(unquote(x) + one()) }; macroexpand(quote(inc(y)))`, `QUOTE((y + 1))`},
+		{`let one = macro() { quote(1) }; let inc = macro(x) { quote(unquote(x) + one()) }; macroexpand(quote(inc(y)), 1)`, `QUOTE((y + one()))`},
+		{`macroexpand(quote(f(1)))`, `QUOTE(f(1))`},
+		{`let inc = macro(x) { quote(unquote(x) + 1) }; let expand = macroexpand; expand(quote(inc(2)))`, `QUOTE((2 + 1))`},
+		{`let inc = macro(x) { quote(unquote(x) + 1) }; let q = quote(inc(2)); [q, macroexpand(q)]`, `[QUOTE(inc(2)), QUOTE((2 + 1))]`},
+		{`let inc = macro(x) { quote(unquote(x) + 1) }; quote(unquote(inc(2)) * 3)`, `QUOTE((3.000000 * 3))`},
+		{`let macroexpand = fn(x) { x + 1 }; macroexpand(1)`, `2.000000`},
+		{`macroexpand`, `native function`},
+	}
+
+	for i, test := range tests {
+		evaluated := evalExpanded(test.input, false)
+		assertions.AssertStringEquals(t, test.expected, evaluated.Inspect(), "test["+strconv.Itoa(i)+"] - evaluated.Inspect() wrong")
+	}
+}
+
+func TestMacroexpandErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`macroexpand()`, "wrong number of arguments. got=0, want=1 or 2"},
+		{`macroexpand(1)`, "argument to `macroexpand` must be QUOTE, got NUMBER"},
+		{`macroexpand(quote(x), 0)`, "depth passed to `macroexpand` must be a positive integer, got 0.000000"},
+		{`let bad = macro() { 1 }; macroexpand(quote(bad()))`, `Error:1:43: macro "bad" must return a quote, got NUMBER`},
+	}
+
+	for i, test := range tests {
+		evaluated := evalExpanded(test.input, false)
+		assertions.AssertTypeOf(t, reflect.TypeOf(object.Error{}), evaluated, "test["+strconv.Itoa(i)+"] - unexpected type")
+		assertions.AssertStringEquals(t, test.expected, evaluated.(*object.Error).Message, "test["+strconv.Itoa(i)+"] - err.Message wrong")
+	}
+}
+
 func parse(input string) *ast.Program {
 	p := parser.New(input)
 	return p.ParseProgram()
 }
+
+func evalExpanded(input string, hygienic bool) object.Object {
+	program := parse(input)
+	env := object.NewEnvironment()
+	e := New()
+	e.Hygienic = hygienic
+	DefineMacros(program, env)
+	expanded, _ := e.ExpandMacros(program, env)
+	return e.Eval(expanded, object.NewEnvironment())
+}
@@ -0,0 +1,55 @@
+package evaluator
+
+import (
+	"fmt"
+	"github.com/digital-codex/monkey/object"
+)
+
+/*****************************************************************************
+ *                                  TYPES                                    *
+ *****************************************************************************/
+
+// Approximate sizes on a 64-bit platform. Only the memory an object owns
+// directly is counted; elements are accounted when they are allocated.
+const (
+	stringSize = 32 // object.String plus its string header
+	arraySize  = 32 // object.Array plus its slice header
+	hashSize   = 48 // object.Hash plus its map header
+	slotSize   = 16 // an object.Object interface value
+	pairSize   = 64 // map entry holding an object.HashKey and object.HashPair
+)
+
+/*****************************************************************************
+ *                             PRIVATE FUNCTIONS                             *
+ *****************************************************************************/
+
+// allocate accounts for obj against the memory limit, returning obj or the
+// error that stops the run once the limit is exceeded.
+func (e *Evaluator) allocate(obj object.Object) object.Object {
+	size := sizeOf(obj)
+	if size == 0 {
+		return obj
+	}
+
+	e.allocations++
+	e.allocated += size
+
+	if e.Limits.Memory > 0 && e.allocated > e.Limits.Memory {
+		return abort(fmt.Errorf("%w: %d bytes", ErrMemoryLimit, e.Limits.Memory))
+	}
+
+	return obj
+}
+
+func sizeOf(obj object.Object) int {
+	switch obj := obj.(type) {
+	case *object.String:
+		return stringSize + len(obj.Value)
+	case *object.Array:
+		return arraySize + slotSize*len(obj.Elements)
+	case *object.Hash:
+		return hashSize + pairSize*len(obj.Pairs)
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,96 @@
+package evaluator
+
+import (
+	"context"
+	"errors"
+	"github.com/digital-codex/assertions"
+	"github.com/digital-codex/monkey/object"
+	"github.com/digital-codex/monkey/parser"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func TestMemoryLimit(t *testing.T) {
+	tests := []struct {
+		input  string
+		memory int
+	}{
+		{`let fill = fn(a, n) { if (n > 0) { fill(push(a, n), n - 1) } else { a } }; fill([], 1000)`, 100000},
+		{`let grow = fn(s, n) { if (n > 0) { grow(s + s, n - 1) } else { s } }; grow("monkey", 30)`, 1 << 20},
+		{`let nest = fn(h, n) { if (n > 0) { nest({"h": h, "n": n}, n - 1) } else { h } }; nest({}, 1000)`, 10000},
+	}
+
+	for i, test := range tests {
+		evaluated := evalWithLimits(context.Background(), test.input, Limits{Memory: test.memory})
+		assertions.AssertTypeOf(t, reflect.TypeOf(object.Error{}), evaluated, "test["+strconv.Itoa(i)+"] - unexpected type")
+		err := evaluated.(*object.Error)
+		assertions.AssertStringEquals(t, "memory limit exceeded: "+strconv.Itoa(test.memory)+" bytes", err.Message, "test["+strconv.Itoa(i)+"] - err.Message wrong")
+		assertions.AssertBoolEquals(t, true, errors.Is(err.Cause, ErrMemoryLimit), "test["+strconv.Itoa(i)+"] - err.Cause wrong")
+	}
+}
+
+func TestMemorySharedResults(t *testing.T) {
+	input := `
+let fill = fn(a, n) { if (n > 0) { fill(push(a, n), n - 1) } else { a } };
+let nested = [fill([], 100)];
+let read = fn(n) { if (n > 0) { first(nested); last(nested); read(n - 1) } else { len(first(nested)) } };
+read(1000)`
+
+	evaluated := evalWithLimits(context.Background(), input, Limits{Memory: 1 << 20})
+	testObject(evaluated)(t, 0, evaluated, 100)
+}
+
+func TestMemoryHostResults(t *testing.T) {
+	cached := &object.Array{Elements: make([]object.Object, 1000)}
+	tests := []struct {
+		allocates   bool
+		allocations int
+		allocated   int
+	}{
+		{false, 0, 0},
+		{true, 3, 3 * (arraySize + 1000*slotSize)},
+	}
+
+	for i, test := range tests {
+		e := New()
+		e.Builtins.Register("cached", 0, "", func(args ...object.Object) object.Object { return cached })
+		if test.allocates {
+			e.Builtins.Allocates("cached")
+		}
+		e.Reset(context.Background())
+		e.Eval(parser.New(`cached(); cached(); cached()`).ParseProgram(), object.NewEnvironment())
+
+		stats := e.Stats()
+		assertions.AssertIntEquals(t, test.allocations, stats.Allocations, "test["+strconv.Itoa(i)+"] - stats.Allocations wrong")
+		assertions.AssertIntEquals(t, test.allocated, stats.Allocated, "test["+strconv.Itoa(i)+"] - stats.Allocated wrong")
+	}
+}
+
+func TestMemoryStats(t *testing.T) {
+	tests := []struct {
+		input       string
+		allocations int
+		allocated   int
+	}{
+		{`1 + 2`, 0, 0},
+		{`"abc"`, 1, stringSize + 3},
+		{`"ab" + "c"`, 3, 3*stringSize + 2 + 1 + 3},
+		{`[1, 2, 3]`, 1, arraySize + 3*slotSize},
+		{`push([1], 2)`, 2, 2*arraySize + 3*slotSize},
+		{`{"a": 1}`, 2, stringSize + 1 + hashSize + pairSize},
+		{`first([[1, 2]])`, 2, 2*arraySize + 3*slotSize},
+		{`last(["a", "bc"])`, 3, 2*stringSize + 3 + arraySize + 2*slotSize},
+		{`rest([[1], [2]])`, 4, 2*arraySize + 2*slotSize + arraySize + 2*slotSize + arraySize + slotSize},
+	}
+
+	for i, test := range tests {
+		e := New()
+		e.Reset(context.Background())
+		e.Eval(parser.New(test.input).ParseProgram(), object.NewEnvironment())
+
+		stats := e.Stats()
+		assertions.AssertIntEquals(t, test.allocations, stats.Allocations, "test["+strconv.Itoa(i)+"] - stats.Allocations wrong")
+		assertions.AssertIntEquals(t, test.allocated, stats.Allocated, "test["+strconv.Itoa(i)+"] - stats.Allocated wrong")
+	}
+}
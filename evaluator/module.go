@@ -0,0 +1,172 @@
+package evaluator
+
+import (
+	"fmt"
+	"github.com/digital-codex/monkey/ast"
+	"github.com/digital-codex/monkey/object"
+	"github.com/digital-codex/monkey/optimizer"
+	"github.com/digital-codex/monkey/parser"
+	"github.com/digital-codex/monkey/resolver"
+	"io/fs"
+	"path"
+	"strings"
+)
+
+/*****************************************************************************
+ *                                  TYPES                                    *
+ *****************************************************************************/
+
+// Loader resolves, evaluates and caches the modules named by import
+// statements. Modules are read from FS only, so the host decides what code is
+// importable. Paths beginning with "./" or "../" are resolved against the
+// directory of the importing module, every other path is looked up in each
+// SearchPath directory in order.
+type Loader struct {
+	FS         fs.FS
+	SearchPath []string
+
+	modules map[string]*object.Module
+	loading []string // paths in FS of the modules currently being evaluated
+}
+
+/*****************************************************************************
+ *                              PUBLIC FUNCTIONS                             *
+ *****************************************************************************/
+
+func NewLoader(fsys fs.FS, searchPath ...string) *Loader {
+	return &Loader{FS: fsys, SearchPath: searchPath, modules: make(map[string]*object.Module)}
+}
+
+// Push marks the module at name as being evaluated, so relative imports made
+// by it resolve against its directory, until the matching Pop.
+func (l *Loader) Push(name string) {
+	l.loading = append(l.loading, name)
+}
+
+func (l *Loader) Pop() {
+	l.loading = l.loading[:len(l.loading)-1]
+}
+
+/*****************************************************************************
+ *                             PRIVATE FUNCTIONS                             *
+ *****************************************************************************/
+
+func (e *Evaluator) importModule(name string) object.Object {
+	l := e.Loader
+
+	if err := e.checkImport(name); err != nil {
+		return err
+	}
+
+	resolved, err := l.resolve(name)
+	if err != nil {
+		return makeError("cannot import %q: %s", name, err)
+	}
+
+	if err := e.checkModule(name, resolved); err != nil {
+		return err
+	}
+
+	if module, ok := l.modules[resolved]; ok {
+		return module
+	}
+
+	for i, loading := range l.loading {
+		if loading == resolved {
+			cycle := strings.Join(l.loading[i:], " -> ")
+			return makeError("import cycle: %s -> %s", cycle, resolved)
+		}
+	}
+
+	source, err := fs.ReadFile(l.FS, resolved)
+	if err != nil {
+		return makeError("cannot import %q: %s", name, err)
+	}
+
+	p := parser.New(string(source))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		return makeError("cannot import %q: %s", name, strings.TrimSpace(p.Errors()[0].Error()))
+	}
+
+	l.Push(resolved)
+	defer l.Pop()
+
+	macros := e.macros
+	defer func() { e.macros = macros }()
+
+	env := object.NewEnvironment()
+	macroEnv := object.NewEnvironment()
+	DefineMacros(program, macroEnv)
+	node, errs := e.ExpandMacros(program, macroEnv)
+	if len(errs) != 0 {
+		return makeError("cannot import %q: %s", name, errs[0])
+	}
+	expanded := node.(*ast.Program)
+	if e.Optimize {
+		expanded = optimizer.Optimize(expanded)
+	}
+
+	r := resolver.New(e.isBuiltin)
+	r.Resolve(expanded)
+	if len(r.Errors()) != 0 {
+		return makeError("cannot import %q: %s", name, r.Errors()[0])
+	}
+
+	evaluated := e.Eval(expanded, env)
+	if err, ok := evaluated.(*object.Error); ok {
+		return &object.Error{Message: fmt.Sprintf("in module %q: %s", name, err.Message), Cause: err.Cause}
+	}
+
+	module := &object.Module{Name: name, Exports: make(map[string]object.Object)}
+	for _, stmt := range program.Statements {
+		if decl, ok := stmt.(*ast.ExportDeclaration); ok {
+			name := decl.Declaration.Name.Value
+			module.Exports[name], _ = env.Get(name)
+		}
+	}
+	l.modules[resolved] = module
+
+	return module
+}
+
+func (l *Loader) resolve(name string) (string, error) {
+	if l.FS == nil {
+		return "", fmt.Errorf("no module filesystem configured")
+	}
+
+	if strings.HasPrefix(name, "/") {
+		return l.validate(path.Clean(strings.TrimPrefix(name, "/")))
+	}
+
+	if isRelativeImport(name) {
+		dir := "."
+		if len(l.loading) > 0 {
+			dir = path.Dir(l.loading[len(l.loading)-1])
+		}
+		return l.validate(path.Join(dir, name))
+	}
+
+	for _, dir := range l.SearchPath {
+		candidate := path.Join(dir, name)
+		if !fs.ValidPath(candidate) {
+			continue
+		}
+		if info, err := fs.Stat(l.FS, candidate); err == nil && !info.IsDir() {
+			return candidate, nil
+		}
+	}
+
+	return "", fmt.Errorf("module not found in search path %v", l.SearchPath)
+}
+
+func (l *Loader) validate(name string) (string, error) {
+	if !fs.ValidPath(name) {
+		return "", fmt.Errorf("path escapes the module filesystem")
+	}
+	return name, nil
+}
+
+func isRelativeImport(name string) bool {
+	return strings.HasPrefix(name, "./") || strings.HasPrefix(name, "../")
+}
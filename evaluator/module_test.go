@@ -0,0 +1,118 @@
+package evaluator
+
+import (
+	"archive/zip"
+	"bytes"
+	"github.com/digital-codex/assertions"
+	"github.com/digital-codex/monkey/object"
+	"github.com/digital-codex/monkey/parser"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestImport(t *testing.T) {
+	fsys := makeModules(map[string]string{
+		"math.mk":       `export let pi = 3; export let double = fn(x) { x * 2 }; let hidden = 1;`,
+		"lib/greet.mk":  `import "./name.mk" as name; export let greeting = "Hello " + name["value"];`,
+		"lib/name.mk":   `export let value = "Monkey";`,
+		"lib/square.mk": `import "../math.mk" as math; export let square = fn(x) { x * x + math["pi"] - math["pi"] };`,
+	})
+
+	tests := []struct {
+		input    string
+		expected any
+	}{
+		{`import "math.mk" as math; math["pi"]`, 3},
+		{`import "math.mk" as math; math["double"](21)`, 42},
+		{`import "lib/greet.mk" as greet; greet["greeting"]`, "Hello Monkey"},
+		{`import "lib/square.mk" as sq; sq["square"](5)`, 25},
+		{`import "math.mk" as a; import "./math.mk" as b; a == b`, true},
+		{`import "/lib/name.mk" as name; name["value"]`, "Monkey"},
+	}
+
+	for i, test := range tests {
+		evaluated := evalWithLoader(test.input, NewLoader(fsys, "."))
+		testObject(evaluated)(t, i, evaluated, test.expected)
+	}
+}
+
+func TestImportErrors(t *testing.T) {
+	fsys := makeModules(map[string]string{
+		"math.mk":    `export let pi = 3; let hidden = 1;`,
+		"a.mk":       `import "b.mk" as b; export let a = 1;`,
+		"b.mk":       `import "a.mk" as a; export let b = 2;`,
+		"broken.mk":  `let = 5;`,
+		"failing.mk": `export let x = 5 + true;`,
+		"unbound.mk": `export let f = fn() { missing };`,
+	})
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`import "math.mk" as math; math["hidden"]`, "hidden is not exported by module math.mk"},
+		{`import "math.mk" as math; math[1]`, "unusable as export name: NUMBER"},
+		{`import "missing.mk" as missing;`, "cannot import \"missing.mk\": module not found"},
+		{`import "a.mk" as a;`, "import cycle: "},
+		{`import "broken.mk" as broken;`, "cannot import \"broken.mk\": "},
+		{`import "failing.mk" as failing;`, "in module \"failing.mk\": type mismatch: NUMBER + BOOLEAN"},
+		{`import "unbound.mk" as unbound;`, "cannot import \"unbound.mk\": Error:1:22: undefined variable \"missing\""},
+		{`import "../outside.mk" as outside;`, "cannot import \"../outside.mk\": path escapes the module filesystem"},
+	}
+
+	for i, test := range tests {
+		evaluated := evalWithLoader(test.input, NewLoader(fsys, "."))
+		assertions.AssertTypeOf(t, reflect.TypeOf(object.Error{}), evaluated, "test["+strconv.Itoa(i)+"] - unexpected type")
+		message := evaluated.(*object.Error).Message
+		assertions.AssertBoolEquals(t, true, strings.Contains(message, test.expected), "test["+strconv.Itoa(i)+"] - err.Message wrong: "+message)
+	}
+}
+
+func TestImportFromZip(t *testing.T) {
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	f, err := w.Create("std/math.mk")
+	if err != nil {
+		t.Fatalf("TestImportFromZip: %s", err)
+	}
+	if _, err := f.Write([]byte(`export let answer = 42;`)); err != nil {
+		t.Fatalf("TestImportFromZip: %s", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("TestImportFromZip: %s", err)
+	}
+
+	r, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("TestImportFromZip: %s", err)
+	}
+
+	evaluated := evalWithLoader(`import "math.mk" as math; math["answer"]`, NewLoader(r, "std"))
+	testObject(evaluated)(t, 0, evaluated, 42)
+}
+
+func TestImportWithoutFS(t *testing.T) {
+	evaluated := eval(`import "math.mk" as math;`)
+	assertions.AssertTypeOf(t, reflect.TypeOf(object.Error{}), evaluated, "unexpected type")
+	assertions.AssertStringEquals(t, "cannot import \"math.mk\": no module filesystem configured", evaluated.(*object.Error).Message, "err.Message wrong")
+}
+
+func evalWithLoader(input string, loader *Loader) object.Object {
+	p := parser.New(input)
+	program := p.ParseProgram()
+	env := object.NewEnvironment()
+	e := New()
+	e.Loader = loader
+	return e.Eval(program, env)
+}
+
+func makeModules(modules map[string]string) fstest.MapFS {
+	fsys := fstest.MapFS{}
+	for name, source := range modules {
+		fsys[name] = &fstest.MapFile{Data: []byte(source)}
+	}
+	return fsys
+}
@@ -11,29 +11,198 @@ import (
  *                             PRIVATE FUNCTIONS                             *
  *****************************************************************************/
 
-func quote(node ast.Node, env *object.Environment) object.Object {
-	node = unquote(node, env)
+// quote works on a copy of node, so the template of a macro comes out intact
+// from every expansion.
+func (e *Evaluator) quote(node ast.Node, env *object.Environment) object.Object {
+	node = ast.Clone(node)
+	unquoted := unquotedNodes(node)
+	template := templateNodes(node, unquoted)
+	node, err := e.unquote(node, unquoted, env)
+	if err != nil {
+		return err
+	}
+	e.rename(node, template, env)
 	return &object.Quote{Node: node}
 }
 
-func unquote(quoted ast.Node, env *object.Environment) ast.Node {
-	return ast.Modify(quoted, func(node ast.Node) ast.Node {
-		if !isUnquotedCall(node) {
+// unquote replaces the calls to unquote in quoted with the code of the values
+// of their arguments, and splices the elements of the arrays passed to
+// unquote_splice into the enclosing argument list, array or block. It stops
+// at the first argument that fails to evaluate or has no code representation.
+// The nodes in unquoted belong to the arguments of those calls and are left
+// for their own evaluation.
+func (e *Evaluator) unquote(quoted ast.Node, unquoted map[ast.Node]bool, env *object.Environment) (ast.Node, *object.Error) {
+	splices := make(map[ast.Node]bool)
+	ast.Modify(quoted, func(node ast.Node) ast.Node {
+		if !unquoted[node] && isSpliceCall(node) {
+			splices[node] = true
+		}
+		return node
+	})
+
+	var err *object.Error
+	node := ast.Modify(quoted, func(node ast.Node) ast.Node {
+		if err != nil || unquoted[node] {
 			return node
 		}
 
-		ce, ok := node.(*ast.CallExpression)
+		switch node := node.(type) {
+		case *ast.CallExpression:
+			if isUnquotedCall(node) && len(node.Argument) == 1 {
+				var converted ast.Node
+				converted, err = e.unquoteValue(node.Argument[0], env)
+				if err != nil {
+					return node
+				}
+				return converted
+			}
+			node.Argument, err = e.spliceExpressions(node.Argument, env)
+		case *ast.ArrayLiteral:
+			node.Elements, err = e.spliceExpressions(node.Elements, env)
+		case *ast.Block:
+			node.Statements, err = e.spliceStatements(node.Statements, env)
+		}
+		return node
+	})
+	if err != nil {
+		return node, err
+	}
+
+	ast.Modify(node, func(node ast.Node) ast.Node {
+		if err == nil && splices[node] {
+			err = makeError("`unquote_splice` must appear in an argument list, an array or a block")
+		}
+		return node
+	})
+
+	return node, err
+}
+
+// unquoteValue returns the code of the value of arg.
+func (e *Evaluator) unquoteValue(arg ast.Expression, env *object.Environment) (ast.Node, *object.Error) {
+	unquoted := e.Eval(arg, env)
+	if isError(unquoted) {
+		return nil, unquoted.(*object.Error)
+	}
+	if unquoted == nil {
+		unquoted = NULL
+	}
+
+	converted := convertObjectToNode(unquoted)
+	if converted == nil {
+		return nil, makeError("cannot unquote %s", unquoted.Type())
+	}
+	if _, ok := converted.(ast.Expression); !ok {
+		return nil, makeError("cannot unquote a %s node into an expression", ast.Kind(converted))
+	}
+	return converted, nil
+}
+
+// splice returns the code of the elements of the array passed to node, when
+// node is a call to unquote_splice.
+func (e *Evaluator) splice(node ast.Node, env *object.Environment) ([]ast.Node, bool, *object.Error) {
+	if !isSpliceCall(node) {
+		return nil, false, nil
+	}
+	ce := node.(*ast.CallExpression)
+	if len(ce.Argument) != 1 {
+		return nil, true, makeError("wrong number of arguments to `unquote_splice`. got=%d, want=1", len(ce.Argument))
+	}
+
+	spliced := e.Eval(ce.Argument[0], env)
+	if isError(spliced) {
+		return nil, true, spliced.(*object.Error)
+	}
+	array, ok := spliced.(*object.Array)
+	if !ok {
+		if spliced == nil {
+			spliced = NULL
+		}
+		return nil, true, makeError("argument to `unquote_splice` must be ARRAY, got %s", spliced.Type())
+	}
+
+	nodes := make([]ast.Node, len(array.Elements))
+	for i, elem := range array.Elements {
+		nodes[i] = convertObjectToNode(elem)
+		if nodes[i] == nil {
+			return nil, true, makeError("cannot unquote %s", elem.Type())
+		}
+	}
+	return nodes, true, nil
+}
+
+func (e *Evaluator) spliceExpressions(exprs []ast.Expression, env *object.Environment) ([]ast.Expression, *object.Error) {
+	spliced := make([]ast.Expression, 0, len(exprs))
+	for _, expr := range exprs {
+		nodes, ok, err := e.splice(expr, env)
+		if err != nil {
+			return exprs, err
+		}
 		if !ok {
-			return node
+			spliced = append(spliced, expr)
+			continue
+		}
+		for _, node := range nodes {
+			expr, ok := node.(ast.Expression)
+			if !ok {
+				return exprs, makeError("cannot splice a %s node into an expression list", ast.Kind(node))
+			}
+			spliced = append(spliced, expr)
 		}
+	}
+	return spliced, nil
+}
 
-		if len(ce.Argument) != 1 {
-			return node
+// spliceStatements splices into a block the nodes passed to unquote_splice
+// calls that stand as statements of their own, wrapping expressions in
+// expression statements.
+func (e *Evaluator) spliceStatements(stmts []ast.Statement, env *object.Environment) ([]ast.Statement, *object.Error) {
+	spliced := make([]ast.Statement, 0, len(stmts))
+	for _, stmt := range stmts {
+		es, ok := stmt.(*ast.ExpressionStatement)
+		if !ok {
+			spliced = append(spliced, stmt)
+			continue
 		}
+		nodes, ok, err := e.splice(es.Expression, env)
+		if err != nil {
+			return stmts, err
+		}
+		if !ok {
+			spliced = append(spliced, stmt)
+			continue
+		}
+		for _, node := range nodes {
+			switch node := node.(type) {
+			case ast.Statement:
+				spliced = append(spliced, node)
+			case ast.Expression:
+				spliced = append(spliced, &ast.ExpressionStatement{Token: es.Token, Expression: node})
+			}
+		}
+	}
+	return spliced, nil
+}
+
+// unquotedNodes returns the nodes inside the arguments of the calls to unquote
+// and unquote_splice in quoted.
+func unquotedNodes(quoted ast.Node) map[ast.Node]bool {
+	unquoted := make(map[ast.Node]bool)
+	mark := func(node ast.Node) ast.Node {
+		unquoted[node] = true
+		return node
+	}
 
-		unquoted := Eval(ce.Argument[0], env)
-		return convertObjectToNode(unquoted)
+	ast.Modify(quoted, func(node ast.Node) ast.Node {
+		if isUnquotedCall(node) || isSpliceCall(node) {
+			for _, arg := range node.(*ast.CallExpression).Argument {
+				ast.Modify(arg, mark)
+			}
+		}
+		return node
 	})
+
+	return unquoted
 }
 
 func isQuoteCall(node ast.Node) bool {
@@ -54,6 +223,15 @@ func isUnquotedCall(node ast.Node) bool {
 	return ce.Function.TokenLexeme() == "unquote"
 }
 
+func isSpliceCall(node ast.Node) bool {
+	ce, ok := node.(*ast.CallExpression)
+	if !ok {
+		return false
+	}
+
+	return ce.Function.TokenLexeme() == "unquote_splice"
+}
+
 func convertObjectToNode(obj object.Object) ast.Node {
 	switch obj := obj.(type) {
 	case *object.Number:
@@ -68,8 +246,36 @@ func convertObjectToNode(obj object.Object) ast.Node {
 		return &ast.Boolean{Token: t, Value: obj.Value}
 	case *object.String:
 		return &ast.StringLiteral{Token: token.Token{Type: token.STRING, Lexeme: obj.Value}, Value: obj.Value}
+	case *object.Null:
+		return &ast.Null{Token: token.Token{Type: token.NULL, Lexeme: "null"}}
+	case *object.Array:
+		elements := make([]ast.Expression, len(obj.Elements))
+		for i, elem := range obj.Elements {
+			node, ok := convertObjectToNode(elem).(ast.Expression)
+			if !ok {
+				return nil
+			}
+			elements[i] = node
+		}
+		return &ast.ArrayLiteral{Token: token.Token{Type: token.LBRACKET, Lexeme: "["}, Elements: elements}
+	case *object.Hash:
+		pairs := make(map[ast.Expression]ast.Expression, len(obj.Pairs))
+		for _, pair := range obj.Pairs {
+			key, ok := convertObjectToNode(pair.Key).(ast.Expression)
+			if !ok {
+				return nil
+			}
+			value, ok := convertObjectToNode(pair.Value).(ast.Expression)
+			if !ok {
+				return nil
+			}
+			pairs[key] = value
+		}
+		return &ast.HashLiteral{Token: token.Token{Type: token.LBRACE, Lexeme: "{"}, Pairs: pairs}
 	case *object.Quote:
 		return obj.Node
+	case *object.Symbol:
+		return &ast.Identifier{Token: token.Token{Type: token.IDENT, Lexeme: obj.Name}, Value: obj.Name}
 	default:
 		return nil
 	}
@@ -42,6 +42,10 @@ func TestUnquote(t *testing.T) {
 		{`quote(unquote(true == false))`, `false`},
 		{`quote(unquote(quote(4 + 4)))`, `(4 + 4)`},
 		{`let quotedInfixExpression = quote(4 + 4); quote(unquote(4 + 4) + unquote(quotedInfixExpression))`, `(8.000000 + (4 + 4))`},
+		{`quote(unquote(null))`, `null`},
+		{`quote(unquote(if (false) { 1 }))`, `null`},
+		{`quote(unquote([1, "two", quote(x)]))`, `[1.000000, two, x]`},
+		{`quote(unquote({"a": [true]}))`, `{a:[true]}`},
 	}
 
 	for i, test := range tests {
@@ -51,3 +55,41 @@ func TestUnquote(t *testing.T) {
 		assertions.AssertStringEquals(t, test.expected, evaluated.(*object.Quote).Node.String(), "test["+strconv.Itoa(i)+"] evaluated.(*object.Quote).Node.String() wrong")
 	}
 }
+
+func TestUnquoteSplice(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`let args = [quote(1), quote(x)]; quote(f(unquote_splice(args)))`, `f(1, x)`},
+		{`quote(f(unquote_splice([])))`, `f()`},
+		{`quote([0, unquote_splice([1, 2]), 3])`, `[0, 1.000000, 2.000000, 3]`},
+		{`quote(fn() { unquote_splice([quote(a), quote(b)]); c })`, `fn(){abc}`},
+		{`let xs = [quote(a), quote(b)]; quote(g(unquote_splice([quote(unquote(first(xs)) + 1), last(xs)])))`, `g((a + 1), b)`},
+	}
+
+	for i, test := range tests {
+		evaluated := eval(test.input)
+		assertions.AssertTypeOf(t, reflect.TypeOf(object.Quote{}), evaluated, "test["+strconv.Itoa(i)+"] - unexpected type")
+		assertions.AssertStringEquals(t, test.expected, evaluated.(*object.Quote).Node.String(), "test["+strconv.Itoa(i)+"] evaluated.(*object.Quote).Node.String() wrong")
+	}
+}
+
+func TestUnquoteErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`quote(unquote(fn() { 1 }))`, "cannot unquote FUNCTION"},
+		{`quote(unquote(missing))`, "identifier not found: missing"},
+		{`quote(1 + unquote_splice([1]))`, "`unquote_splice` must appear in an argument list, an array or a block"},
+		{`quote(f(unquote_splice(1)))`, "argument to `unquote_splice` must be ARRAY, got NUMBER"},
+		{`quote(f(unquote_splice([len])))`, "cannot unquote BUILTIN"},
+	}
+
+	for i, test := range tests {
+		evaluated := eval(test.input)
+		assertions.AssertTypeOf(t, reflect.TypeOf(object.Error{}), evaluated, "test["+strconv.Itoa(i)+"] - unexpected type")
+		assertions.AssertStringEquals(t, test.expected, evaluated.(*object.Error).Message, "test["+strconv.Itoa(i)+"] - err.Message wrong")
+	}
+}
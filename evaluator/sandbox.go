@@ -0,0 +1,115 @@
+package evaluator
+
+import (
+	"errors"
+	"fmt"
+	"github.com/digital-codex/monkey/object"
+	"path"
+	"strings"
+)
+
+/*****************************************************************************
+ *                                  TYPES                                    *
+ *****************************************************************************/
+
+// Capability is a set of effects a script may have on the world outside the
+// interpreter.
+type Capability int
+
+const (
+	STDOUT Capability = 1 << iota // write to standard output
+	STDERR                        // write to standard error
+	STDIN                         // read from standard input
+	IMPORT                        // import modules
+
+	ALL Capability = STDOUT | STDERR | STDIN | IMPORT
+)
+
+var capabilities = [...]string{
+	"stdout",
+	"stderr",
+	"stdin",
+	"import",
+}
+
+// Profile names the capabilities granted to the scripts of an Evaluator.
+// When Modules is not empty, only the modules whose resolved path matches
+// one of its path.Match patterns may be imported.
+type Profile struct {
+	Name         string
+	Capabilities Capability
+	Modules      []string
+}
+
+var (
+	PURE        = Profile{Name: "pure"}
+	STDOUT_ONLY = Profile{Name: "stdout-only", Capabilities: STDOUT}
+	FULL        = Profile{Name: "full", Capabilities: ALL}
+)
+
+var ErrPermission = errors.New("permission denied")
+
+/*****************************************************************************
+ *                              PUBLIC FUNCTIONS                             *
+ *****************************************************************************/
+
+// LookupProfile returns the predefined profile called name.
+func LookupProfile(name string) (Profile, bool) {
+	for _, profile := range []Profile{PURE, STDOUT_ONLY, FULL} {
+		if profile.Name == name {
+			return profile, true
+		}
+	}
+	return Profile{}, false
+}
+
+func (c Capability) String() string {
+	var names []string
+	for i, name := range capabilities {
+		if c&(1<<i) != 0 {
+			names = append(names, name)
+		}
+	}
+	return strings.Join(names, "|")
+}
+
+func (p Profile) Allows(c Capability) bool {
+	return p.Capabilities&c == c
+}
+
+// Require records that the builtin name needs capabilities c, so it is only
+// visible to scripts whose profile allows them.
+func (b *Builtins) Require(name string, c Capability) {
+	b.requires[name] = c
+}
+
+/*****************************************************************************
+ *                             PRIVATE FUNCTIONS                             *
+ *****************************************************************************/
+
+func (e *Evaluator) checkBuiltin(name string) *object.Error {
+	required := e.Builtins.requires[name]
+	if e.Profile.Allows(required) {
+		return nil
+	}
+	return abort(fmt.Errorf("%w: `%s` requires %s in sandbox profile %s", ErrPermission, name, required, e.Profile.Name))
+}
+
+func (e *Evaluator) checkImport(name string) *object.Error {
+	if !e.Profile.Allows(IMPORT) {
+		return abort(fmt.Errorf("%w: importing %q requires %s in sandbox profile %s", ErrPermission, name, IMPORT, e.Profile.Name))
+	}
+	return nil
+}
+
+func (e *Evaluator) checkModule(name string, resolved string) *object.Error {
+	if len(e.Profile.Modules) == 0 {
+		return nil
+	}
+	for _, pattern := range e.Profile.Modules {
+		if ok, _ := path.Match(pattern, resolved); ok {
+			return nil
+		}
+	}
+	return abort(fmt.Errorf("%w: module %q is not visible in sandbox profile %s", ErrPermission, name, e.Profile.Name))
+}
@@ -0,0 +1,95 @@
+package evaluator
+
+import (
+	"errors"
+	"github.com/digital-codex/assertions"
+	"github.com/digital-codex/monkey/object"
+	"github.com/digital-codex/monkey/parser"
+	"reflect"
+	"strconv"
+	"testing"
+	"testing/fstest"
+)
+
+func TestSandboxAllowed(t *testing.T) {
+	fsys := makeModules(map[string]string{
+		"std/math.mk": `export let pi = 3;`,
+	})
+
+	tests := []struct {
+		input    string
+		profile  Profile
+		expected any
+	}{
+		{`len("abc")`, PURE, 3},
+		{`let puts = fn(x) { x }; puts(1)`, PURE, 1},
+		{`let say = puts; say == puts`, STDOUT_ONLY, true},
+		{`import "std/math.mk" as math; math["pi"]`, FULL, 3},
+		{`import "std/math.mk" as math; math["pi"]`, Profile{Name: "std", Capabilities: IMPORT, Modules: []string{"std/*"}}, 3},
+	}
+
+	for i, test := range tests {
+		evaluated := evalWithProfile(test.input, fsys, test.profile)
+		testObject(evaluated)(t, i, evaluated, test.expected)
+	}
+}
+
+func TestSandboxDenied(t *testing.T) {
+	fsys := makeModules(map[string]string{
+		"std/math.mk": `export let pi = 3;`,
+		"lib/util.mk": `export let one = 1;`,
+		"lib/io.mk":   `export let say = fn(x) { puts(x) };`,
+	})
+
+	tests := []struct {
+		input    string
+		profile  Profile
+		expected string
+	}{
+		{`puts("hello")`, PURE, "permission denied: `puts` requires stdout in sandbox profile pure"},
+		{`readLine()`, STDOUT_ONLY, "permission denied: `readLine` requires stdin in sandbox profile stdout-only"},
+		{`eprint("oops")`, STDOUT_ONLY, "permission denied: `eprint` requires stderr in sandbox profile stdout-only"},
+		{`let say = puts; 1`, PURE, "permission denied: `puts` requires stdout in sandbox profile pure"},
+		{`import "std/math.mk" as math;`, PURE, "permission denied: importing \"std/math.mk\" requires import in sandbox profile pure"},
+		{`import "std/math.mk" as math;`, STDOUT_ONLY, "permission denied: importing \"std/math.mk\" requires import in sandbox profile stdout-only"},
+		{`import "lib/util.mk" as util;`, Profile{Name: "std", Capabilities: IMPORT, Modules: []string{"std/*"}}, "permission denied: module \"lib/util.mk\" is not visible in sandbox profile std"},
+		{`import "lib/io.mk" as io; io["say"]("hi")`, Profile{Name: "quiet", Capabilities: IMPORT}, "permission denied: `puts` requires stdout in sandbox profile quiet"},
+	}
+
+	for i, test := range tests {
+		evaluated := evalWithProfile(test.input, fsys, test.profile)
+		assertions.AssertTypeOf(t, reflect.TypeOf(object.Error{}), evaluated, "test["+strconv.Itoa(i)+"] - unexpected type")
+		err := evaluated.(*object.Error)
+		assertions.AssertStringEquals(t, test.expected, err.Message, "test["+strconv.Itoa(i)+"] - err.Message wrong")
+		assertions.AssertBoolEquals(t, true, errors.Is(err.Cause, ErrPermission), "test["+strconv.Itoa(i)+"] - err.Cause wrong")
+	}
+}
+
+func TestLookupProfile(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected Capability
+		ok       bool
+	}{
+		{"pure", 0, true},
+		{"stdout-only", STDOUT, true},
+		{"full", ALL, true},
+		{"none", 0, false},
+	}
+
+	for i, test := range tests {
+		profile, ok := LookupProfile(test.name)
+		assertions.AssertBoolEquals(t, test.ok, ok, "test["+strconv.Itoa(i)+"] - ok wrong")
+		assertions.AssertIntEquals(t, int(test.expected), int(profile.Capabilities), "test["+strconv.Itoa(i)+"] - profile.Capabilities wrong")
+	}
+}
+
+func evalWithProfile(input string, fsys fstest.MapFS, profile Profile) object.Object {
+	p := parser.New(input)
+	program := p.ParseProgram()
+	env := object.NewEnvironment()
+	e := New()
+	e.Loader = NewLoader(fsys, ".")
+	e.Profile = profile
+	return e.Eval(program, env)
+}
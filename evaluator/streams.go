@@ -0,0 +1,56 @@
+package evaluator
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"strings"
+)
+
+/*****************************************************************************
+ *                                  TYPES                                    *
+ *****************************************************************************/
+
+// Streams are the standard streams used by the I/O builtins of a registry.
+// The fields may be replaced between runs.
+type Streams struct {
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+
+	reader *bufio.Reader // buffers Stdin across calls to readLine
+	source io.Reader     // the Stdin reader is reading from
+}
+
+/*****************************************************************************
+ *                              PUBLIC FUNCTIONS                             *
+ *****************************************************************************/
+
+// DefaultStreams returns streams connected to those of the process.
+func DefaultStreams() *Streams {
+	return &Streams{Stdin: os.Stdin, Stdout: os.Stdout, Stderr: os.Stderr}
+}
+
+/*****************************************************************************
+ *                             PRIVATE FUNCTIONS                             *
+ *****************************************************************************/
+
+// readLine returns the next line of Stdin without its line terminator, or
+// io.EOF once Stdin is exhausted.
+func (s *Streams) readLine() (string, error) {
+	if s.reader == nil || s.source != s.Stdin {
+		s.reader = bufio.NewReader(s.Stdin)
+		s.source = s.Stdin
+	}
+
+	line, err := s.reader.ReadString('\n')
+	if err == io.EOF && line != "" {
+		err = nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	line = strings.TrimSuffix(line, "\n")
+	return strings.TrimSuffix(line, "\r"), nil
+}
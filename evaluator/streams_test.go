@@ -0,0 +1,51 @@
+package evaluator
+
+import (
+	"bytes"
+	"github.com/digital-codex/assertions"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestStreams(t *testing.T) {
+	tests := []struct {
+		input    string
+		stdin    string
+		expected any
+		stdout   string
+		stderr   string
+	}{
+		{`puts("a", 1, true)`, "", NULL, "a\n1.000000\ntrue\n", ""},
+		{`print("a", 1); print("b")`, "", NULL, "a 1.000000b", ""},
+		{`eprint("oops")`, "", NULL, "", "oops\n"},
+		{`readLine()`, "first\nsecond\n", "first", "", ""},
+		{`readLine(); readLine()`, "first\r\nsecond", "second", "", ""},
+		{`readLine(); readLine()`, "first\n", NULL, "", ""},
+		{`let line = readLine(); puts(line + "!")`, "hi\n", NULL, "hi!\n", ""},
+	}
+
+	for i, test := range tests {
+		var stdout, stderr bytes.Buffer
+		builtins := DefaultBuiltins()
+		streams := builtins.Streams()
+		streams.Stdin = strings.NewReader(test.stdin)
+		streams.Stdout = &stdout
+		streams.Stderr = &stderr
+
+		evaluated := evalWithBuiltins(test.input, builtins)
+		testObject(evaluated)(t, i, evaluated, test.expected)
+		assertions.AssertStringEquals(t, test.stdout, stdout.String(), "test["+strconv.Itoa(i)+"] - stdout wrong")
+		assertions.AssertStringEquals(t, test.stderr, stderr.String(), "test["+strconv.Itoa(i)+"] - stderr wrong")
+	}
+}
+
+func TestScopedStreams(t *testing.T) {
+	var stdout bytes.Buffer
+	builtins := DefaultBuiltins()
+	scoped := builtins.Scope("puts")
+	builtins.Streams().Stdout = &stdout
+
+	evalWithBuiltins(`puts("shared")`, scoped)
+	assertions.AssertStringEquals(t, "shared\n", stdout.String(), "scoped registry should share streams")
+}
@@ -0,0 +1,102 @@
+package evaluator
+
+import (
+	"github.com/digital-codex/monkey/ast"
+	"github.com/digital-codex/monkey/object"
+)
+
+/*****************************************************************************
+ *                             PRIVATE FUNCTIONS                             *
+ *****************************************************************************/
+
+// evalTail evaluates node, whose value is the result of the enclosing
+// function, like Eval. A call in tail position is not performed but returned
+// as an object.TailCall for the trampoline in call.
+func (e *Evaluator) evalTail(node ast.Node, env *object.Environment) object.Object {
+	switch node := node.(type) {
+	case *ast.Block:
+		if err := e.step(); err != nil {
+			return err
+		}
+		return e.evalTailBlock(node, env)
+	case *ast.ReturnStatement:
+		if err := e.step(); err != nil {
+			return err
+		}
+		return e.evalTail(node.ReturnValue, env)
+	case *ast.ExpressionStatement:
+		if err := e.step(); err != nil {
+			return err
+		}
+		return e.evalTail(node.Expression, env)
+	case *ast.GroupedExpression:
+		if err := e.step(); err != nil {
+			return err
+		}
+		return e.evalTail(node.Expression, env)
+	case *ast.IfExpression:
+		if err := e.step(); err != nil {
+			return err
+		}
+		return e.evalTailIfExpression(node, env)
+	case *ast.CallExpression:
+		if isQuoteCall(node) {
+			return e.Eval(node, env)
+		}
+		if err := e.step(); err != nil {
+			return err
+		}
+		return e.evalTailCallExpression(node, env)
+	default:
+		return e.Eval(node, env)
+	}
+}
+
+func (e *Evaluator) evalTailBlock(node *ast.Block, env *object.Environment) object.Object {
+	var result object.Object
+
+	for i, statement := range node.Statements {
+		if _, ok := statement.(*ast.ReturnStatement); ok || i == len(node.Statements)-1 {
+			return e.evalTail(statement, env)
+		}
+
+		result = e.Eval(statement, env)
+
+		if result != nil {
+			rt := result.Type()
+			if rt == object.RETURN_VALUE || rt == object.ERROR {
+				return result
+			}
+		}
+	}
+
+	return result
+}
+
+func (e *Evaluator) evalTailIfExpression(node *ast.IfExpression, env *object.Environment) object.Object {
+	condition := e.Eval(node.Condition, env)
+	if isError(condition) {
+		return condition
+	}
+
+	if isTruthy(condition) {
+		return e.evalTail(node.Consequence, env)
+	} else if node.Alternative != nil {
+		return e.evalTail(node.Alternative, env)
+	}
+
+	return NULL
+}
+
+func (e *Evaluator) evalTailCallExpression(node *ast.CallExpression, env *object.Environment) object.Object {
+	fn := e.Eval(node.Function, env)
+	if isError(fn) {
+		return fn
+	}
+	args := e.evalExpressions(node.Argument, env)
+	if len(args) == 1 && isError(args[0]) {
+		return args[0]
+	}
+
+	return &object.TailCall{Function: fn, Arguments: args}
+}
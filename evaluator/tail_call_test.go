@@ -0,0 +1,55 @@
+package evaluator
+
+import (
+	"context"
+	"errors"
+	"github.com/digital-codex/assertions"
+	"github.com/digital-codex/monkey/object"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func TestTailCalls(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected any
+	}{
+		{`let count = fn(n, acc) { if (n == 0) { acc } else { count(n - 1, acc + 1) } }; count(1000000, 0)`, 1000000},
+		{`let count = fn(n) { if (n == 0) { return 0; } return count(n - 1); }; count(1000000)`, 0},
+		{`let count = fn(n) { if (n > 0) { return count(n - 1); } else { return n; } }; count(100000)`, 0},
+		{`let count = fn(n) { if (n == 0) { return true; } let m = n - 1; (count(m)) }; count(100000)`, true},
+		{`let even = fn(n) { if (n == 0) { true } else { odd(n - 1) } }; let odd = fn(n) { if (n == 0) { false } else { even(n - 1) } }; even(100001)`, false},
+		{`let sum = fn(a, acc) { if (len(a) == 0) { acc } else { sum(rest(a), acc + first(a)) } }; sum([1, 2, 3, 4], 0)`, 10},
+		{`let f = fn(x) { len(x) }; f("abc")`, 3},
+		{`let f = fn(n) { if (n == 0) { "done" } else { f(n - 1) } }; let g = fn() { f(10) + "!" }; g()`, "done!"},
+	}
+
+	for i, test := range tests {
+		evaluated := evalWithLimits(nil, test.input, Limits{Depth: 100})
+		testObject(evaluated)(t, i, evaluated, test.expected)
+	}
+}
+
+func TestTailCallErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`let f = fn(n) { if (n == 0) { n + true } else { f(n - 1) } }; f(100000)`, "type mismatch: NUMBER + BOOLEAN"},
+		{`let f = fn(n) { if (n == 0) { 5(n) } else { f(n - 1) } }; f(10)`, "not a function: NUMBER"},
+		{`let f = fn(n) { len(n, n) }; f(1)`, "wrong number of arguments. got=2, want=1"},
+	}
+
+	for i, test := range tests {
+		evaluated := eval(test.input)
+		assertions.AssertTypeOf(t, reflect.TypeOf(object.Error{}), evaluated, "test["+strconv.Itoa(i)+"] - unexpected type")
+		assertions.AssertStringEquals(t, test.expected, evaluated.(*object.Error).Message, "test["+strconv.Itoa(i)+"] - err.Message wrong")
+	}
+}
+
+func TestTailCallLimits(t *testing.T) {
+	evaluated := evalWithLimits(context.Background(), `let f = fn() { f() }; f()`, Limits{Steps: 100000})
+	assertions.AssertTypeOf(t, reflect.TypeOf(object.Error{}), evaluated, "unexpected type")
+	assertions.AssertBoolEquals(t, true, errors.Is(evaluated.(*object.Error).Cause, ErrStepLimit), "infinite tail recursion should hit the step limit")
+}
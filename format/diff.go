@@ -0,0 +1,219 @@
+package format
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+/*****************************************************************************
+ *                                  TYPES                                    *
+ *****************************************************************************/
+
+const CONTEXT = 3 // the unchanged lines shown around a change
+
+// edit is a line of a diff: kept (' '), removed ('-') or added ('+').
+type edit struct {
+	op   byte
+	text string
+	a, b int // the lines before the edit in old and new
+}
+
+/*****************************************************************************
+ *                              PUBLIC FUNCTIONS                             *
+ *****************************************************************************/
+
+// Diff returns the changes from old to new in the unified format, naming both
+// sides after name, or nil when they are the same.
+func Diff(name string, old, new []byte) []byte {
+	if bytes.Equal(old, new) {
+		return nil
+	}
+
+	edits := diffLines(lines(old), lines(new))
+
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "--- %s.orig\n+++ %s\n", name, name)
+	for start := 0; start < len(edits); {
+		// find the next change and the end of the hunk around it
+		first := start
+		for first < len(edits) && edits[first].op == ' ' {
+			first++
+		}
+		if first == len(edits) {
+			break
+		}
+		from := max(first-CONTEXT, start)
+		to := first
+		for unchanged := 0; to < len(edits) && unchanged <= 2*CONTEXT; to++ {
+			if edits[to].op == ' ' {
+				unchanged++
+			} else {
+				unchanged = 0
+			}
+		}
+		for to > first && edits[to-1].op == ' ' {
+			to--
+		}
+		to = min(to+CONTEXT, len(edits))
+
+		hunk(&out, edits[from:to])
+		start = to
+	}
+	return out.Bytes()
+}
+
+/*****************************************************************************
+ *                             PRIVATE FUNCTIONS                             *
+ *****************************************************************************/
+
+func lines(text []byte) []string {
+	if len(text) == 0 {
+		return nil
+	}
+	split := strings.SplitAfter(string(text), "\n")
+	if split[len(split)-1] == "" {
+		split = split[:len(split)-1]
+	}
+	return split
+}
+
+// diffLines returns the edits turning a into b, keeping a longest common
+// subsequence of their lines. It uses the linear space variant of the
+// algorithm of Myers, so the memory it needs grows with the number of lines
+// rather than with their product.
+func diffLines(a, b []string) []edit {
+	return appendDiff(nil, a, b, 0, 0)
+}
+
+// appendDiff appends the edits turning a into b to edits, where a and b start
+// after the lines i and j of the files compared. The lines a and b start and
+// end with are kept without searching, then the rest is split around the
+// middle snake of a shortest edit script and each part diffed in turn.
+func appendDiff(edits []edit, a, b []string, i, j int) []edit {
+	for len(a) > 0 && len(b) > 0 && a[0] == b[0] {
+		edits = append(edits, edit{' ', a[0], i, j})
+		a, b = a[1:], b[1:]
+		i, j = i+1, j+1
+	}
+	suffix := 0
+	for suffix < len(a) && suffix < len(b) && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	n, m := len(a)-suffix, len(b)-suffix
+
+	switch {
+	case n == 0:
+		for k := 0; k < m; k++ {
+			edits = append(edits, edit{'+', b[k], i, j + k})
+		}
+	case m == 0:
+		for k := 0; k < n; k++ {
+			edits = append(edits, edit{'-', a[k], i + k, j})
+		}
+	default:
+		x, y, u, v := middleSnake(a[:n], b[:m])
+		edits = appendDiff(edits, a[:x], b[:y], i, j)
+		for k := 0; k < u-x; k++ {
+			edits = append(edits, edit{' ', a[x+k], i + x + k, j + y + k})
+		}
+		edits = appendDiff(edits, a[u:n], b[v:m], i+u, j+v)
+	}
+
+	for k := 0; k < suffix; k++ {
+		edits = append(edits, edit{' ', a[n+k], i + n + k, j + m + k})
+	}
+	return edits
+}
+
+// middleSnake returns the start (x, y) and the end (u, v) of the middle snake
+// of a shortest edit script turning a into b: the lines a[x:u] equal to b[y:v]
+// where the paths searched forward from the start and backward from the end
+// meet. a and b must not be empty.
+func middleSnake(a, b []string) (x, y, u, v int) {
+	n, m := len(a), len(b)
+	delta := n - m
+	limit := (n + m + 1) / 2
+	offset := limit + 1
+
+	// forward[offset+k] is the furthest x reached on the diagonal x - y = k
+	// from the start, backward[offset+k] the same from the end, counting x and
+	// y backwards from n and m
+	forward := make([]int, 2*offset+1)
+	backward := make([]int, 2*offset+1)
+
+	for d := 0; d <= limit; d++ {
+		for k := -d; k <= d; k += 2 {
+			if k == -d || (k != d && forward[offset+k-1] < forward[offset+k+1]) {
+				x = forward[offset+k+1]
+			} else {
+				x = forward[offset+k-1] + 1
+			}
+			y = x - k
+			u, v = x, y
+			for u < n && v < m && a[u] == b[v] {
+				u, v = u+1, v+1
+			}
+			forward[offset+k] = u
+
+			if c := delta - k; delta%2 != 0 && -(d-1) <= c && c <= d-1 && u >= n-backward[offset+c] {
+				return x, y, u, v
+			}
+		}
+
+		for k := -d; k <= d; k += 2 {
+			var rx, ry int
+			if k == -d || (k != d && backward[offset+k-1] < backward[offset+k+1]) {
+				rx = backward[offset+k+1]
+			} else {
+				rx = backward[offset+k-1] + 1
+			}
+			ry = rx - k
+			ru, rv := rx, ry
+			for ru < n && rv < m && a[n-1-ru] == b[m-1-rv] {
+				ru, rv = ru+1, rv+1
+			}
+			backward[offset+k] = ru
+
+			if c := delta - k; delta%2 == 0 && -d <= c && c <= d && forward[offset+c] >= n-ru {
+				return n - ru, m - rv, n - rx, m - ry
+			}
+		}
+	}
+
+	// not reached: the paths meet within limit steps
+	return 0, 0, 0, 0
+}
+
+func hunk(out *bytes.Buffer, edits []edit) {
+	var removed, added int
+	for _, e := range edits {
+		if e.op != '+' {
+			removed++
+		}
+		if e.op != '-' {
+			added++
+		}
+	}
+
+	fmt.Fprintf(out, "@@ -%s +%s @@\n", span(edits[0].a, removed), span(edits[0].b, added))
+	for _, e := range edits {
+		out.WriteByte(e.op)
+		out.WriteString(e.text)
+		if !strings.HasSuffix(e.text, "\n") {
+			out.WriteString("\n\\ No newline at end of file\n")
+		}
+	}
+}
+
+// span returns the range of a hunk header for count lines after the first
+// lines of a file.
+func span(first, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", first)
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", first+1)
+	}
+	return fmt.Sprintf("%d,%d", first+1, count)
+}
@@ -0,0 +1,99 @@
+package format
+
+import (
+	"github.com/digital-codex/assertions"
+	"math/rand"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		old, new string
+		expected string
+	}{
+		{"a\nb\n", "a\nb\n", ""},
+		{"a\nb\nc\n", "a\nB\nc\n", "--- f.mk.orig\n+++ f.mk\n@@ -1,3 +1,3 @@\n a\n-b\n+B\n c\n"},
+		{"", "a\n", "--- f.mk.orig\n+++ f.mk\n@@ -0,0 +1 @@\n+a\n"},
+		{"a", "a\n", "--- f.mk.orig\n+++ f.mk\n@@ -1 +1 @@\n-a\n\\ No newline at end of file\n+a\n"},
+		{
+			"1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n",
+			"0\n1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n",
+			"--- f.mk.orig\n+++ f.mk\n@@ -1,3 +1,4 @@\n+0\n 1\n 2\n 3\n@@ -9,4 +10,3 @@\n 9\n 10\n 11\n-12\n",
+		},
+	}
+
+	for i, test := range tests {
+		actual := Diff("f.mk", []byte(test.old), []byte(test.new))
+		assertions.AssertStringEquals(t, test.expected, string(actual), "test["+strconv.Itoa(i)+"] - Diff() wrong")
+	}
+}
+
+func TestDiffLines(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+	for i := 0; i < 1000; i++ {
+		a := randomLines(random, random.Intn(12))
+		b := randomLines(random, random.Intn(12))
+		edits := diffLines(a, b)
+
+		var old, new []string
+		kept := 0
+		for _, e := range edits {
+			if e.op != '+' {
+				assertions.AssertIntEquals(t, len(old), e.a, "test["+strconv.Itoa(i)+"] - edit.a wrong")
+				old = append(old, e.text)
+			}
+			if e.op != '-' {
+				assertions.AssertIntEquals(t, len(new), e.b, "test["+strconv.Itoa(i)+"] - edit.b wrong")
+				new = append(new, e.text)
+			}
+			if e.op == ' ' {
+				kept++
+			}
+		}
+		assertions.AssertStringEquals(t, strings.Join(a, ""), strings.Join(old, ""), "test["+strconv.Itoa(i)+"] - old lines wrong")
+		assertions.AssertStringEquals(t, strings.Join(b, ""), strings.Join(new, ""), "test["+strconv.Itoa(i)+"] - new lines wrong")
+		assertions.AssertIntEquals(t, lcsLength(a, b), kept, "test["+strconv.Itoa(i)+"] - kept lines wrong")
+	}
+}
+
+func TestDiffLarge(t *testing.T) {
+	old := make([]string, 50000)
+	for i := range old {
+		old[i] = "line " + strconv.Itoa(i) + "\n"
+	}
+	new := append([]string{}, old...)
+	new[100] = "changed\n"
+	new = append(new[:40000], new[40001:]...)
+
+	diff := Diff("f.mk", []byte(strings.Join(old, "")), []byte(strings.Join(new, "")))
+	assertions.AssertBoolEquals(t, true, strings.Contains(string(diff), "-line 100\n+changed\n"), "changed line missing")
+	assertions.AssertBoolEquals(t, true, strings.Contains(string(diff), "-line 40000\n"), "removed line missing")
+}
+
+func randomLines(random *rand.Rand, n int) []string {
+	lines := make([]string, n)
+	for i := range lines {
+		lines[i] = string(rune('a'+random.Intn(3))) + "\n"
+	}
+	return lines
+}
+
+// lcsLength returns the length of the longest common subsequence of a and b.
+func lcsLength(a, b []string) int {
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+	return lcs[0][0]
+}
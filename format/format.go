@@ -0,0 +1,606 @@
+package format
+
+import (
+	"bytes"
+	"github.com/digital-codex/monkey/ast"
+	"github.com/digital-codex/monkey/lexer"
+	"github.com/digital-codex/monkey/parser"
+	"github.com/digital-codex/monkey/token"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+/*****************************************************************************
+ *                                  TYPES                                    *
+ *****************************************************************************/
+
+const (
+	INDENT    = "    "
+	MAX_WIDTH = 80 // the width past which argument lists are wrapped
+)
+
+// ParseError reports the syntax errors that kept Source from formatting.
+type ParseError struct {
+	Errors []error
+}
+
+// printer writes the canonical form of a tree. Formatting source, it also
+// knows the comments that are still to be written, which the tree does not
+// record.
+type printer struct {
+	out    bytes.Buffer
+	indent int
+
+	source   bool
+	comments []token.Token
+	line     int // the last source line written
+}
+
+// item is an element of a list, with the tokens it spans in the source.
+type item struct {
+	first, last token.Token
+	print       func(p *printer)
+}
+
+/*****************************************************************************
+ *                              PUBLIC FUNCTIONS                             *
+ *****************************************************************************/
+
+// Source formats the program in src. Statements are laid out one per line and
+// blocks indented by INDENT, argument lists, arrays and hashes too long for a
+// line of MAX_WIDTH are wrapped one element per line, and comments and single
+// blank lines between statements are kept. A comment stays at the end of the
+// line of the token it follows, or on a line of its own. Formatting the result
+// again does not change it.
+func Source(src []byte) ([]byte, error) {
+	p := parser.New(string(src))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		return nil, &ParseError{Errors: p.Errors()}
+	}
+
+	pr := &printer{source: true}
+	pr.scan(string(src))
+	pr.statements(program.Statements, true, math.MaxInt)
+	if pr.out.Len() > 0 {
+		pr.out.WriteByte('\n')
+	}
+	return pr.out.Bytes(), nil
+}
+
+// Node formats node, which need not come from source code, the way Source
+// would format it without comments.
+func Node(node ast.Node) string {
+	pr := &printer{}
+	switch node := node.(type) {
+	case *ast.Program:
+		pr.statements(node.Statements, true, math.MaxInt)
+	case ast.Statement:
+		pr.statement(node, true)
+	case ast.Expression:
+		pr.expression(node)
+	}
+	return pr.out.String()
+}
+
+func (pe *ParseError) Error() string {
+	var msgs []string
+	for _, err := range pe.Errors {
+		msgs = append(msgs, strings.TrimSpace(err.Error()))
+	}
+	return strings.Join(msgs, "\n")
+}
+
+/*****************************************************************************
+ *                             PRIVATE FUNCTIONS                             *
+ *****************************************************************************/
+
+// scan reads the comments of src.
+func (p *printer) scan(src string) {
+	l := lexer.New(src, nil)
+	for t := l.Next(); t.Type != token.EOF; t = l.Next() {
+	}
+	p.comments = l.Comments()
+}
+
+func (p *printer) write(s string) {
+	p.out.WriteString(s)
+}
+
+func (p *printer) newline() {
+	p.newlineAt(p.indent)
+}
+
+func (p *printer) newlineAt(indent int) {
+	p.out.WriteByte('\n')
+	p.out.WriteString(strings.Repeat(INDENT, indent))
+}
+
+// separate starts a new line for what is on line in the source, after an
+// empty line when the source had one there.
+func (p *printer) separate(line int) {
+	p.out.WriteByte('\n')
+	if p.source && p.line > 0 && line > p.line+1 {
+		p.out.WriteByte('\n')
+	}
+	p.out.WriteString(strings.Repeat(INDENT, p.indent))
+}
+
+// currentLine returns what has been written of the current line.
+func (p *printer) currentLine() string {
+	out := p.out.Bytes()
+	return string(out[bytes.LastIndexByte(out, '\n')+1:])
+}
+
+// fork returns a printer that goes on from the current line without writing
+// to p, to see how something would be laid out.
+func (p *printer) fork() *printer {
+	sub := &printer{indent: p.indent, source: p.source, comments: p.comments, line: p.line}
+	sub.out.WriteString(p.currentLine())
+	return sub
+}
+
+// commentBefore reports whether a comment is still to be written before the
+// offset end.
+func (p *printer) commentBefore(end int) bool {
+	return len(p.comments) > 0 && p.comments[0].Start < end
+}
+
+// commentWithin reports whether a comment is still to be written between the
+// offsets start and end.
+func (p *printer) commentWithin(start, end int) bool {
+	for _, c := range p.comments {
+		if c.Start >= end {
+			return false
+		}
+		if c.Start > start {
+			return true
+		}
+	}
+	return false
+}
+
+// lineComments writes the comments before the offset end each on a line of
+// its own, starting a new line first when started. It reports whether
+// anything has been written.
+func (p *printer) lineComments(end int, started bool) bool {
+	for p.commentBefore(end) {
+		c := p.comments[0]
+		p.comments = p.comments[1:]
+		if started {
+			p.separate(c.Line)
+		}
+		p.write(c.Lexeme)
+		p.line = c.Line
+		started = true
+	}
+	return started
+}
+
+// trailing writes a comment that follows the source line of last on that same
+// line, before the offset end.
+func (p *printer) trailing(last token.Token, end int) {
+	if p.commentBefore(end) && p.comments[0].Line == last.Line && p.comments[0].Start > last.Start {
+		p.write(" " + p.comments[0].Lexeme)
+		p.comments = p.comments[1:]
+	}
+	p.line = last.Line
+}
+
+// token is called before writing the source token t. The comments before it
+// are still to be written, so each is written at the end of the line of the
+// token before it, or on a line of its own when it was on one in the source,
+// and t goes on a new line indented once more than the current one.
+func (p *printer) token(t token.Token) {
+	if !p.source || t.Length == 0 {
+		return
+	}
+	p.interject(t.Start, p.indent+1)
+	p.line = t.Line
+}
+
+// interject writes the comments before the offset end, each at the end of the
+// current line when it was on the same line in the source and on a line of its
+// own otherwise, then starts a new line indented indent times. It reports
+// whether anything has been written.
+func (p *printer) interject(end int, indent int) bool {
+	if !p.commentBefore(end) {
+		return false
+	}
+	for p.commentBefore(end) {
+		c := p.comments[0]
+		p.comments = p.comments[1:]
+		p.out.Truncate(len(bytes.TrimRight(p.out.Bytes(), " ")))
+		if c.Line == p.line {
+			p.write(" ")
+		} else {
+			p.newlineAt(indent)
+		}
+		p.write(c.Lexeme)
+		p.line = c.Line
+	}
+	p.newlineAt(indent)
+	return true
+}
+
+// statements writes stmts one per line, followed by the comments before the
+// offset end.
+func (p *printer) statements(stmts []ast.Statement, top bool, end int) {
+	started := false
+	for i, stmt := range stmts {
+		first := ast.First(stmt)
+		if p.lineComments(first.Start, started) || started {
+			p.separate(first.Line)
+		}
+		var following ast.Statement
+		if i < len(stmts)-1 {
+			following = stmts[i+1]
+		}
+		p.statement(stmt, terminated(stmt, following, top))
+		started = true
+
+		next := end
+		if following != nil {
+			next = ast.First(following).Start
+		}
+		p.trailing(ast.Last(stmt), next)
+	}
+	p.lineComments(end, started)
+}
+
+func (p *printer) statement(stmt ast.Statement, terminated bool) {
+	switch stmt := stmt.(type) {
+	case *ast.LetDeclaration:
+		p.write("let ")
+		p.expression(stmt.Name)
+		p.write(" = ")
+		p.expression(stmt.Value)
+		p.write(";")
+	case *ast.ImportStatement:
+		p.write("import ")
+		p.expression(stmt.Path)
+		p.write(" as ")
+		p.expression(stmt.Name)
+		p.write(";")
+	case *ast.ExportDeclaration:
+		p.write("export ")
+		p.statement(stmt.Declaration, true)
+	case *ast.ReturnStatement:
+		p.write("return ")
+		p.expression(stmt.ReturnValue)
+		p.write(";")
+	case *ast.ExpressionStatement:
+		p.expression(stmt.Expression)
+		if terminated {
+			p.write(";")
+		}
+	case *ast.Block:
+		p.block(stmt)
+	}
+}
+
+func (p *printer) block(block *ast.Block) {
+	end := p.end(block.Closing)
+
+	if len(block.Statements) == 0 && !p.commentBefore(end) {
+		p.token(block.Token)
+		p.write("{}")
+		p.line = block.Closing.Line
+		return
+	}
+
+	p.write("{")
+	if p.source && p.commentBefore(block.Token.Start) {
+		// a comment in the header, e.g. before the closing parenthesis of a
+		// condition, ends the line of the brace instead of breaking the header
+		p.write(" " + p.comments[0].Lexeme)
+		p.comments = p.comments[1:]
+		p.line = block.Token.Line
+	} else if len(block.Statements) > 0 {
+		p.trailing(block.Token, ast.First(block.Statements[0]).Start)
+	} else {
+		p.trailing(block.Token, end)
+	}
+	if len(block.Statements) > 0 || p.commentBefore(end) {
+		p.indent++
+		p.newline()
+		p.line = 0
+		p.statements(block.Statements, false, end)
+		p.indent--
+	}
+	p.newline()
+	p.write("}")
+	p.line = block.Closing.Line
+}
+
+func (p *printer) expression(exp ast.Expression) {
+	switch exp := exp.(type) {
+	case *ast.Identifier:
+		p.token(exp.Token)
+		p.write(exp.Value)
+	case *ast.NumberLiteral:
+		p.token(exp.Token)
+		if exp.Token.Lexeme != "" && exp.Token.Type == token.NUMBER {
+			p.write(exp.Token.Lexeme)
+		} else {
+			p.write(strconv.FormatFloat(exp.Value, 'f', -1, 64))
+		}
+	case *ast.StringLiteral:
+		p.token(exp.Token)
+		p.write("\"" + exp.Value + "\"")
+	case *ast.Boolean:
+		p.token(exp.Token)
+		p.write(strconv.FormatBool(exp.Value))
+	case *ast.Null:
+		p.token(exp.Token)
+		p.write("null")
+	case *ast.PrefixExpression:
+		p.token(exp.Token)
+		p.write(exp.Operator)
+		p.operand(exp.Right, precedence(exp.Right) < parser.UNARY)
+	case *ast.InfixExpression:
+		prec := operatorPrecedence(exp.Operator)
+		p.operand(exp.Left, precedence(exp.Left) < prec)
+		p.write(" ")
+		p.token(exp.Token)
+		p.write(exp.Operator + " ")
+		p.operand(exp.Right, precedence(exp.Right) <= prec)
+	case *ast.GroupedExpression:
+		p.token(exp.Token)
+		p.write("(")
+		p.expression(exp.Expression)
+		p.token(exp.Closing)
+		p.write(")")
+	case *ast.IfExpression:
+		p.token(exp.Token)
+		p.write("if (")
+		p.expression(exp.Condition)
+		p.write(") ")
+		p.block(exp.Consequence)
+		if exp.Alternative != nil {
+			// a comment after the consequence puts else on the next line
+			if p.source && p.interject(exp.Alternative.Token.Start, p.indent) {
+				p.write("else ")
+			} else {
+				p.write(" else ")
+			}
+			p.block(exp.Alternative)
+		}
+	case *ast.FunctionLiteral:
+		p.token(exp.Token)
+		p.write("fn(")
+		p.parameters(exp.Parameters)
+		p.write(") ")
+		p.block(exp.Body)
+	case *ast.MacroLiteral:
+		p.token(exp.Token)
+		p.write("macro(")
+		p.parameters(exp.Parameters)
+		p.write(") ")
+		p.block(exp.Body)
+	case *ast.CallExpression:
+		p.operand(exp.Function, precedence(exp.Function) < parser.CALL)
+		p.list("(", ")", exp.Token, exp.Closing, p.expressionItems(exp.Argument))
+	case *ast.ArrayLiteral:
+		p.list("[", "]", exp.Token, exp.Closing, p.expressionItems(exp.Elements))
+	case *ast.IndexExpression:
+		p.operand(exp.Left, precedence(exp.Left) < parser.CALL)
+		p.token(exp.Token)
+		p.write("[")
+		p.expression(exp.Index)
+		p.token(exp.Closing)
+		p.write("]")
+	case *ast.HashLiteral:
+		p.list("{", "}", exp.Token, exp.Closing, p.pairItems(exp))
+	}
+}
+
+// operand writes exp, in parentheses when the operator it belongs to binds
+// tighter than exp does.
+func (p *printer) operand(exp ast.Expression, parenthesize bool) {
+	if parenthesize {
+		p.write("(")
+		p.expression(exp)
+		p.write(")")
+	} else {
+		p.expression(exp)
+	}
+}
+
+func (p *printer) expressionItems(exps []ast.Expression) []item {
+	items := make([]item, len(exps))
+	for i, exp := range exps {
+		exp := exp
+		items[i] = item{first: ast.First(exp), last: ast.Last(exp), print: func(p *printer) { p.expression(exp) }}
+	}
+	return items
+}
+
+// pairItems returns the pairs of a hash in source order, or ordered by their
+// keys for a hash that was not parsed.
+func (p *printer) pairItems(hash *ast.HashLiteral) []item {
+	var keys []ast.Expression
+	for i, child := range ast.Children(hash) {
+		if i%2 == 0 {
+			keys = append(keys, child.(ast.Expression))
+		}
+	}
+	if p.source {
+		sort.SliceStable(keys, func(i, j int) bool {
+			return ast.First(keys[i]).Start < ast.First(keys[j]).Start
+		})
+	}
+
+	items := make([]item, len(keys))
+	for i, key := range keys {
+		key, value := key, hash.Pairs[key]
+		items[i] = item{first: ast.First(key), last: ast.Last(value), print: func(p *printer) {
+			p.expression(key)
+			p.write(": ")
+			p.expression(value)
+		}}
+	}
+	return items
+}
+
+// list writes items between the brackets open and close, found in the source
+// at the tokens opening and closing, on the current line when they fit in
+// MAX_WIDTH and one per line otherwise or when there are comments among them.
+func (p *printer) list(open, close string, opening, closing token.Token, items []item) {
+	end := p.end(closing)
+	p.token(opening)
+
+	if !p.source || !p.commentWithin(opening.Start, end) {
+		if len(items) == 0 {
+			p.write(open + close)
+			p.line = closing.Line
+			return
+		}
+
+		sub := p.fork()
+		prefix := sub.out.Len()
+		sub.write(open)
+		for i, it := range items {
+			if i > 0 {
+				sub.write(", ")
+			}
+			it.print(sub)
+		}
+		sub.write(close)
+
+		out := sub.out.String()
+		if first, _, _ := strings.Cut(out, "\n"); len(first) <= MAX_WIDTH {
+			p.write(out[prefix:])
+			p.comments = sub.comments
+			p.line = sub.line
+			return
+		}
+	}
+
+	p.write(open)
+	if len(items) > 0 {
+		p.trailing(opening, items[0].first.Start)
+	} else {
+		p.trailing(opening, end)
+	}
+	p.indent++
+	for i, it := range items {
+		p.newline()
+		if p.lineComments(it.first.Start, false) {
+			p.newline()
+		}
+		it.print(p)
+		if i < len(items)-1 {
+			p.write(",")
+		}
+
+		next := end
+		if i < len(items)-1 {
+			next = items[i+1].first.Start
+		}
+		p.trailing(it.last, next)
+	}
+	if p.commentBefore(end) {
+		p.newline()
+		p.lineComments(end, false)
+	}
+	p.indent--
+	p.newline()
+	p.write(close)
+}
+
+// end returns the offset of the closing bracket closing in the source, or
+// math.MaxInt when there is no source.
+func (p *printer) end(closing token.Token) int {
+	if !p.source || closing.Lexeme == "" {
+		return math.MaxInt
+	}
+	return closing.Start
+}
+
+// terminated reports whether stmt, followed by next, ends with a semicolon.
+// Declarations always do and expressions do unless they are the last of a
+// block, which gives the block its value, or end in a block that next cannot
+// be read as continuing.
+func terminated(stmt ast.Statement, next ast.Statement, top bool) bool {
+	es, ok := stmt.(*ast.ExpressionStatement)
+	if !ok {
+		return true
+	}
+
+	switch es.Expression.(type) {
+	case *ast.IfExpression, *ast.FunctionLiteral, *ast.MacroLiteral:
+		return next != nil && continues(next)
+	}
+	return next != nil || top
+}
+
+// continues reports whether stmt starts with a token that would carry on the
+// expression before it, such as the parenthesis of a call.
+func continues(stmt ast.Statement) bool {
+	es, ok := stmt.(*ast.ExpressionStatement)
+	if !ok {
+		return false
+	}
+
+	exp := es.Expression
+	for {
+		switch e := exp.(type) {
+		case *ast.InfixExpression:
+			exp = e.Left
+		case *ast.CallExpression:
+			exp = e.Function
+		case *ast.IndexExpression:
+			exp = e.Left
+		case *ast.GroupedExpression, *ast.ArrayLiteral:
+			return true
+		case *ast.PrefixExpression:
+			return e.Operator == "-"
+		case *ast.NumberLiteral:
+			return e.Value < 0
+		default:
+			return false
+		}
+	}
+}
+
+// precedence returns how tightly exp binds, as the parser ranks operators.
+func precedence(exp ast.Expression) parser.Precedence {
+	switch exp := exp.(type) {
+	case *ast.InfixExpression:
+		return operatorPrecedence(exp.Operator)
+	case *ast.PrefixExpression:
+		return parser.UNARY
+	case *ast.NumberLiteral:
+		if exp.Value < 0 {
+			return parser.UNARY
+		}
+	}
+	return parser.INDEX
+}
+
+func operatorPrecedence(operator string) parser.Precedence {
+	switch operator {
+	case "==", "!=":
+		return parser.EQUALITY
+	case "<", ">":
+		return parser.COMPARISON
+	case "+", "-":
+		return parser.TERM
+	case "*", "/":
+		return parser.FACTOR
+	default:
+		return parser.NONE
+	}
+}
+
+func (p *printer) parameters(params []*ast.Identifier) {
+	for i, param := range params {
+		if i > 0 {
+			p.write(", ")
+		}
+		p.expression(param)
+	}
+}
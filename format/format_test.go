@@ -0,0 +1,147 @@
+package format
+
+import (
+	"github.com/digital-codex/assertions"
+	"github.com/digital-codex/monkey/ast"
+	"github.com/digital-codex/monkey/parser"
+	"github.com/digital-codex/monkey/token"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func TestSource(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{``, ``},
+		{`let x=1`, "let x = 1;\n"},
+		{`1+2*3; (1+2)*3`, "1 + 2 * 3;\n(1 + 2) * 3;\n"},
+		{`-a[0]; !f(x)(y)`, "-a[0];\n!f(x)(y);\n"},
+		{`import "lib.mk" as lib
+export let z = lib["v"]`, "import \"lib.mk\" as lib;\nexport let z = lib[\"v\"];\n"},
+		{`let f=fn(a,b){let c=a+b;return c;}`, "let f = fn(a, b) {\n    let c = a + b;\n    return c;\n};\n"},
+		{`let m = macro(x) { quote(unquote(x)) }`, "let m = macro(x) {\n    quote(unquote(x))\n};\n"},
+		{`if (a) { 1 } else { 2 }`, "if (a) {\n    1\n} else {\n    2\n}\n"},
+		{`if (a) { 1 }
+(b)`, "if (a) {\n    1\n}(b);\n"},
+		{`fn() { if (a) { b } c }`, "fn() {\n    if (a) {\n        b\n    }\n    c\n}\n"},
+		{`let e = fn() {}; let h = {}; let a = []; f()`, "let e = fn() {};\nlet h = {};\nlet a = [];\nf();\n"},
+		{`{"b": 2, "a": [1, null, true],}`, "{\"b\": 2, \"a\": [1, null, true]};\n"},
+		{`let x = 1;
+
+
+let y = 2; let z = 3;`, "let x = 1;\n\nlet y = 2;\nlet z = 3;\n"},
+		{`map([1, 2], fn(x) { x * 2 })`, "map([1, 2], fn(x) {\n    x * 2\n});\n"},
+		{`let long = someFunction(aVeryLongArgumentName, anotherVeryLongArgumentName, yetAnother);`,
+			"let long = someFunction(\n    aVeryLongArgumentName,\n    anotherVeryLongArgumentName,\n    yetAnother\n);\n"},
+		{`let names = ["aVeryLongArgumentName", "anotherVeryLongArgumentName", "yetAnother"];`,
+			"let names = [\n    \"aVeryLongArgumentName\",\n    \"anotherVeryLongArgumentName\",\n    \"yetAnother\"\n];\n"},
+		// comments
+		{`// only`, "// only\n"},
+		{`// header
+
+let x = 1; // one
+// before y
+let y = 2;
+// end`, "// header\n\nlet x = 1; // one\n// before y\nlet y = 2;\n// end\n"},
+		{`let f = fn() { // open
+  1 // value
+  // close
+}`, "let f = fn() { // open\n    1 // value\n    // close\n};\n"},
+		{`if (a) { // nothing
+}`, "if (a) { // nothing\n}\n"},
+		{`f(a, // first
+b)`, "f(\n    a, // first\n    b\n);\n"},
+		{`f(
+  // lead
+  a)`, "f(\n    // lead\n    a\n);\n"},
+		{`let x = 1 + // inside
+2;`, "let x = 1 + // inside\n    2;\n"},
+		{`let x = 1 + // inside
+2;
+let y = 3;`, "let x = 1 + // inside\n    2;\nlet y = 3;\n"},
+		{`if (a) { 1 } // then
+else { 2 }`, "if (a) {\n    1\n} // then\nelse {\n    2\n}\n"},
+		{`if (a) { 1 }
+// then
+else { 2 }`, "if (a) {\n    1\n}\n// then\nelse {\n    2\n}\n"},
+		{`let f = fn(x, // first
+y) { x };`, "let f = fn(x, // first\n    y) {\n    x\n};\n"},
+		{`let h = {"k": // key
+1};`, "let h = {\n    \"k\": // key\n        1\n};\n"},
+		{`if (x // cond
+) { 1 }`, "if (x) { // cond\n    1\n}\n"},
+		{`let f = fn(a, b // last
+) { a };`, "let f = fn(a, b) { // last\n    a\n};\n"},
+		{`if (x // one
+// two
+) { 1 }`, "if (x) { // one\n    // two\n    1\n}\n"},
+		{`if (x // cond
+) {}`, "if (x) { // cond\n}\n"},
+		{`f( // none
+)`, "f( // none\n);\n"},
+		{`f(1 +
+// operand
+2)`, "f(\n    1 +\n        // operand\n        2\n);\n"},
+	}
+
+	for i, test := range tests {
+		formatted, err := Source([]byte(test.input))
+		assertions.AssertEquals(t, nil, err, "test["+strconv.Itoa(i)+"] - unexpected err")
+		assertions.AssertStringEquals(t, test.expected, string(formatted), "test["+strconv.Itoa(i)+"] - formatted wrong")
+
+		again, err := Source(formatted)
+		assertions.AssertEquals(t, nil, err, "test["+strconv.Itoa(i)+"] - unexpected err")
+		assertions.AssertStringEquals(t, string(formatted), string(again), "test["+strconv.Itoa(i)+"] - not idempotent")
+
+		assertions.AssertBoolEquals(t, true, ast.Equal(parse(t, test.input), parse(t, string(formatted))), "test["+strconv.Itoa(i)+"] - program changed")
+	}
+}
+
+func TestSourceErrors(t *testing.T) {
+	_, err := Source([]byte(`let = 1;`))
+	assertions.AssertTypeOf(t, reflect.TypeOf(ParseError{}), err, "err type wrong")
+	assertions.AssertStringEquals(t, "Error:1:4: unexpected token wanted \"=\"\nError:1:4: expect expression got \"=\"", err.Error(), "err.Error() wrong")
+}
+
+func TestNode(t *testing.T) {
+	number := func(value float64) *ast.NumberLiteral { return &ast.NumberLiteral{Value: value} }
+	ident := func(name string) *ast.Identifier { return &ast.Identifier{Value: name} }
+
+	tests := []struct {
+		input    ast.Node
+		expected string
+	}{
+		{&ast.InfixExpression{Left: &ast.InfixExpression{Left: number(1), Operator: "+", Right: number(2)}, Operator: "*", Right: number(3)}, `(1 + 2) * 3`},
+		{&ast.InfixExpression{Left: number(1), Operator: "-", Right: &ast.InfixExpression{Left: number(2), Operator: "-", Right: number(3)}}, `1 - (2 - 3)`},
+		{&ast.InfixExpression{Left: &ast.InfixExpression{Left: number(1), Operator: "-", Right: number(2)}, Operator: "-", Right: number(3)}, `1 - 2 - 3`},
+		{&ast.PrefixExpression{Operator: "-", Right: &ast.InfixExpression{Left: ident("a"), Operator: "+", Right: number(0.5)}}, `-(a + 0.5)`},
+		{&ast.CallExpression{Function: &ast.PrefixExpression{Operator: "!", Right: ident("f")}, Argument: []ast.Expression{number(-1)}}, `(!f)(-1)`},
+		{&ast.IndexExpression{Left: number(-1), Index: number(0)}, `(-1)[0]`},
+		{&ast.LetDeclaration{Name: ident("x"), Value: &ast.StringLiteral{Value: "s"}}, `let x = "s";`},
+		{&ast.Program{Statements: []ast.Statement{
+			&ast.ExpressionStatement{Expression: &ast.FunctionLiteral{Parameters: []*ast.Identifier{ident("a")}, Body: &ast.Block{Statements: []ast.Statement{
+				&ast.ExpressionStatement{Expression: ident("a")},
+			}}}},
+			&ast.ExpressionStatement{Expression: &ast.HashLiteral{Pairs: map[ast.Expression]ast.Expression{
+				&ast.StringLiteral{Token: token.Token{Lexeme: "b"}, Value: "b"}: &ast.Boolean{Value: false},
+				&ast.StringLiteral{Token: token.Token{Lexeme: "a"}, Value: "a"}: &ast.Null{},
+			}}},
+		}}, "fn(a) {\n    a\n}\n{\"a\": null, \"b\": false};"},
+	}
+
+	for i, test := range tests {
+		assertions.AssertStringEquals(t, test.expected, Node(test.input), "test["+strconv.Itoa(i)+"] - Node() wrong")
+	}
+}
+
+func parse(t *testing.T, input string) *ast.Program {
+	p := parser.New(input)
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser has %d errors: %v", len(p.Errors()), p.Errors())
+	}
+	return program
+}
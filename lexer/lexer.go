@@ -31,6 +31,8 @@ type Lexer struct {
 
 	eh       ErrorHandler
 	errorCnt int
+
+	comments []token.Token
 }
 
 var keywords = map[string]token.Type{
@@ -40,7 +42,10 @@ var keywords = map[string]token.Type{
 	"else":   token.ELSE,
 	"true":   token.TRUE,
 	"false":  token.FALSE,
+	"null":   token.NULL,
 	"macro":  token.MACRO,
+	"export": token.EXPORT,
+	"import": token.IMPORT,
 	"return": token.RETURN,
 }
 
@@ -49,7 +54,7 @@ var keywords = map[string]token.Type{
  *****************************************************************************/
 
 func New(input string, eh ErrorHandler) *Lexer {
-	return &Lexer{input, 0, 0, 1, 0, eh, 0}
+	return &Lexer{input, 0, 0, 1, 0, eh, 0, nil}
 }
 
 func (l *Lexer) Next() token.Token {
@@ -78,7 +83,7 @@ func (l *Lexer) Next() token.Token {
 			return l.emit(token.STAR)
 		case '/':
 			if l.match('/') {
-				l.skip(isNotNLAndEOF)
+				l.comment()
 			} else {
 				return l.emit(token.SLASH)
 			}
@@ -122,10 +127,23 @@ func (l *Lexer) Next() token.Token {
 	return l.emit(token.EOF)
 }
 
+// Comments returns the comments skipped so far, as COMMENT tokens whose
+// lexeme is the text of the comment from the leading "//".
+func (l *Lexer) Comments() []token.Token {
+	return l.comments
+}
+
 /*****************************************************************************
  *                             PRIVATE FUNCTIONS                             *
  *****************************************************************************/
 
+func (l *Lexer) comment() {
+	l.skip(isNotNLAndEOF)
+	comment := l.emitWithLexeme(token.COMMENT, strings.TrimRight(l.source[l.start:l.current], " \t\r"))
+	comment.Length = len(comment.Lexeme)
+	l.comments = append(l.comments, comment)
+}
+
 func (l *Lexer) ident() token.Token {
 	lit := l.read(isAlphaNumeric)
 	t := token.IDENT
@@ -246,6 +246,26 @@ func TestNext(t *testing.T) {
 				{Type: token.EOF, Lexeme: ""},
 			},
 		},
+		{
+			`import "math.mk" as math; export let pi = math["pi"];`,
+			[]token.Token{
+				{Type: token.IMPORT, Lexeme: "import"},
+				{Type: token.STRING, Lexeme: "math.mk"},
+				{Type: token.IDENT, Lexeme: "as"},
+				{Type: token.IDENT, Lexeme: "math"},
+				{Type: token.SEMICOLON, Lexeme: ";"},
+				{Type: token.EXPORT, Lexeme: "export"},
+				{Type: token.LET, Lexeme: "let"},
+				{Type: token.IDENT, Lexeme: "pi"},
+				{Type: token.EQUAL, Lexeme: "="},
+				{Type: token.IDENT, Lexeme: "math"},
+				{Type: token.LBRACKET, Lexeme: "["},
+				{Type: token.STRING, Lexeme: "pi"},
+				{Type: token.RBRACKET, Lexeme: "]"},
+				{Type: token.SEMICOLON, Lexeme: ";"},
+				{Type: token.EOF, Lexeme: ""},
+			},
+		},
 	}
 
 	for i, test := range tests {
@@ -258,6 +278,21 @@ func TestNext(t *testing.T) {
 	}
 }
 
+func TestComments(t *testing.T) {
+	input := "// header\nlet a = 1; // one  \r\n//\nlet b = a / 2;// two"
+	expected := []token.Token{
+		{Type: token.COMMENT, Lexeme: "// header", Line: 1, Start: 0, Length: 9},
+		{Type: token.COMMENT, Lexeme: "// one", Line: 2, Start: 21, Length: 6},
+		{Type: token.COMMENT, Lexeme: "//", Line: 3, Start: 31, Length: 2},
+		{Type: token.COMMENT, Lexeme: "// two", Line: 4, Start: 48, Length: 6},
+	}
+
+	l := New(input, LogError)
+	for l.Next().Type != token.EOF {
+	}
+	assertions.AssertDeepEquals(t, expected, l.Comments(), "l.Comments() wrong")
+}
+
 func TestErrors(t *testing.T) {
 	tests := []struct {
 		input    string
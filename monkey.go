@@ -0,0 +1,324 @@
+package monkey
+
+import (
+	"context"
+	"fmt"
+	"github.com/digital-codex/monkey/ast"
+	"github.com/digital-codex/monkey/evaluator"
+	"github.com/digital-codex/monkey/object"
+	"github.com/digital-codex/monkey/optimizer"
+	"github.com/digital-codex/monkey/parser"
+	"github.com/digital-codex/monkey/resolver"
+	"io"
+	"io/fs"
+	"strings"
+)
+
+/*****************************************************************************
+ *                                  TYPES                                    *
+ *****************************************************************************/
+
+// Interpreter runs Monkey programs on behalf of a Go host. Globals, macros and
+// imported modules persist across calls to Run and RunFile.
+type Interpreter struct {
+	evaluator *evaluator.Evaluator
+	env       *object.Environment
+	macroEnv  *object.Environment
+	dump      io.Writer // receives each optimized program, if set
+}
+
+type ParseError struct {
+	Errors []error
+}
+
+// ResolveError reports references to undefined variables, found before the
+// program runs.
+type ResolveError struct {
+	Errors []error
+}
+
+// MacroError reports macro calls that could not be expanded, e.g. because
+// the macro failed or did not return a quote.
+type MacroError struct {
+	Errors []error
+}
+
+type RuntimeError struct {
+	Message string
+	Err     error // set when evaluation was stopped, e.g. evaluator.ErrStepLimit
+}
+
+/*****************************************************************************
+ *                              PUBLIC FUNCTIONS                             *
+ *****************************************************************************/
+
+// New returns an Interpreter that imports modules from fsys. A nil fsys
+// disables import statements.
+func New(fsys fs.FS) *Interpreter {
+	e := evaluator.New()
+	e.Loader = evaluator.NewLoader(fsys, ".")
+
+	return &Interpreter{
+		evaluator: e,
+		env:       object.NewEnvironment(),
+		macroEnv:  object.NewEnvironment(),
+	}
+}
+
+// Run evaluates src and returns the value of its last statement, which is nil
+// when that statement is a declaration. The names used by src are resolved
+// before it runs, so they must be defined by src, by an earlier run or as
+// builtins: a function can no longer refer to a global that a later run
+// defines, and such a reference is a ResolveError.
+func (i *Interpreter) Run(src string) (object.Object, error) {
+	return i.RunContext(context.Background(), src)
+}
+
+// RunContext is like Run but stops evaluation once ctx is done.
+func (i *Interpreter) RunContext(ctx context.Context, src string) (object.Object, error) {
+	i.evaluator.Reset(ctx)
+
+	p := parser.New(src)
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		return nil, &ParseError{Errors: p.Errors()}
+	}
+
+	evaluator.DefineMacros(program, i.macroEnv)
+	node, errs := i.evaluator.ExpandMacros(program, i.macroEnv)
+	if len(errs) != 0 {
+		return nil, &MacroError{Errors: errs}
+	}
+	expanded := node.(*ast.Program)
+	if i.evaluator.Optimize {
+		expanded = optimizer.Optimize(expanded)
+		if i.dump != nil {
+			dumpProgram(i.dump, expanded)
+		}
+	}
+
+	r := resolver.New(i.defined)
+	r.Resolve(expanded)
+	if len(r.Errors()) != 0 {
+		return nil, &ResolveError{Errors: r.Errors()}
+	}
+
+	return result(i.evaluator.Eval(expanded, i.env))
+}
+
+// Expand returns src with its macro calls expanded, up to depth levels when
+// depth is not 0, see evaluator.Evaluator.ExpandMacrosDepth. The macros
+// defined by earlier runs are visible, but those defined by src are not kept.
+func (i *Interpreter) Expand(src string, depth int) (*ast.Program, error) {
+	i.evaluator.Reset(context.Background())
+
+	p := parser.New(src)
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		return nil, &ParseError{Errors: p.Errors()}
+	}
+
+	env := object.NewEnclosedEnvironment(i.macroEnv)
+	evaluator.DefineMacros(program, env)
+	expanded, errs := i.evaluator.ExpandMacrosDepth(program, env, depth)
+	if len(errs) != 0 {
+		return nil, &MacroError{Errors: errs}
+	}
+
+	return expanded.(*ast.Program), nil
+}
+
+// RunFile evaluates the file at path in the interpreter's filesystem. Relative
+// imports made by the file resolve against its directory.
+func (i *Interpreter) RunFile(path string) (object.Object, error) {
+	return i.RunFileContext(context.Background(), path)
+}
+
+// RunFileContext is like RunFile but stops evaluation once ctx is done.
+func (i *Interpreter) RunFileContext(ctx context.Context, path string) (object.Object, error) {
+	fsys := i.evaluator.Loader.FS
+	if fsys == nil {
+		return nil, fmt.Errorf("cannot run %q: no filesystem configured", path)
+	}
+
+	source, err := fs.ReadFile(fsys, path)
+	if err != nil {
+		return nil, err
+	}
+
+	i.evaluator.Loader.Push(path)
+	defer i.evaluator.Loader.Pop()
+
+	return i.RunContext(ctx, string(source))
+}
+
+// Call calls the global function name with args, each converted by ToObject.
+func (i *Interpreter) Call(name string, args ...any) (object.Object, error) {
+	return i.CallContext(context.Background(), name, args...)
+}
+
+// CallContext is like Call but stops evaluation once ctx is done.
+func (i *Interpreter) CallContext(ctx context.Context, name string, args ...any) (object.Object, error) {
+	fn, ok := i.env.Get(name)
+	if !ok {
+		return nil, fmt.Errorf("identifier not found: %s", name)
+	}
+
+	objs := make([]object.Object, len(args))
+	for n, arg := range args {
+		obj, err := ToObject(arg)
+		if err != nil {
+			return nil, err
+		}
+		objs[n] = obj
+	}
+
+	i.evaluator.Reset(ctx)
+	return result(i.evaluator.Apply(fn, objs...))
+}
+
+// Set binds the global name to value, converted by ToObject.
+func (i *Interpreter) Set(name string, value any) error {
+	obj, err := ToObject(value)
+	if err != nil {
+		return err
+	}
+	i.env.Set(name, obj)
+	return nil
+}
+
+func (i *Interpreter) Get(name string) (object.Object, bool) {
+	return i.env.Get(name)
+}
+
+// Names returns the names bound by earlier runs and Set in sorted order.
+func (i *Interpreter) Names() []string {
+	return i.env.Names()
+}
+
+// SetLimits bounds the steps, call depth and memory of every later run.
+// Exceeding them fails the run with a RuntimeError wrapping
+// evaluator.ErrStepLimit, evaluator.ErrCallDepth or evaluator.ErrMemoryLimit.
+func (i *Interpreter) SetLimits(limits evaluator.Limits) {
+	i.evaluator.Limits = limits
+}
+
+// SetProfile restricts the builtins and modules visible to later runs. Denied
+// accesses fail the run with a RuntimeError wrapping evaluator.ErrPermission.
+func (i *Interpreter) SetProfile(profile evaluator.Profile) {
+	i.evaluator.Profile = profile
+}
+
+// SetStdin sets the reader used by readLine.
+func (i *Interpreter) SetStdin(r io.Reader) {
+	i.evaluator.Builtins.Streams().Stdin = r
+}
+
+// SetStdout sets the writer used by puts and print.
+func (i *Interpreter) SetStdout(w io.Writer) {
+	i.evaluator.Builtins.Streams().Stdout = w
+}
+
+// SetStderr sets the writer used by eprint.
+func (i *Interpreter) SetStderr(w io.Writer) {
+	i.evaluator.Builtins.Streams().Stderr = w
+}
+
+// EnableOptimizer makes later runs, and the modules they import, optimize
+// programs before evaluating them, see optimizer.Optimize. When dump is not
+// nil each optimized program is written to it, one statement per line.
+func (i *Interpreter) EnableOptimizer(dump io.Writer) {
+	i.evaluator.Optimize = true
+	i.dump = dump
+}
+
+// SetHygienic makes the macros expanded by later runs hygienic: the names
+// bound inside quote by a macro are renamed at each expansion, so they cannot
+// capture the identifiers passed to the macro. Parts spliced in with unquote
+// keep their names.
+func (i *Interpreter) SetHygienic(enabled bool) {
+	i.evaluator.Hygienic = enabled
+}
+
+// Stats returns the work done by the last run, whether or not it succeeded.
+func (i *Interpreter) Stats() evaluator.Stats {
+	return i.evaluator.Stats()
+}
+
+// Builtins returns the registry of native functions visible to scripts, which
+// starts out holding evaluator.DefaultBuiltins.
+func (i *Interpreter) Builtins() *evaluator.Builtins {
+	return i.evaluator.Builtins
+}
+
+// SetBuiltins replaces the native functions visible to scripts, e.g. with a
+// registry narrowed by Builtins.Scope. Its streams replace those set by
+// SetStdin, SetStdout and SetStderr.
+func (i *Interpreter) SetBuiltins(builtins *evaluator.Builtins) {
+	i.evaluator.Builtins = builtins
+}
+
+func (pe *ParseError) Error() string {
+	var msgs []string
+	for _, err := range pe.Errors {
+		msgs = append(msgs, strings.TrimSpace(err.Error()))
+	}
+	return strings.Join(msgs, "\n")
+}
+
+func (re *ResolveError) Error() string {
+	var msgs []string
+	for _, err := range re.Errors {
+		msgs = append(msgs, err.Error())
+	}
+	return strings.Join(msgs, "\n")
+}
+
+func (me *MacroError) Error() string {
+	var msgs []string
+	for _, err := range me.Errors {
+		msgs = append(msgs, err.Error())
+	}
+	return strings.Join(msgs, "\n")
+}
+
+func (me *MacroError) Unwrap() []error {
+	return me.Errors
+}
+
+func (re *RuntimeError) Error() string {
+	return re.Message
+}
+
+func (re *RuntimeError) Unwrap() error {
+	return re.Err
+}
+
+/*****************************************************************************
+ *                             PRIVATE FUNCTIONS                             *
+ *****************************************************************************/
+
+// defined reports whether name is bound by an earlier run or is a builtin.
+func (i *Interpreter) defined(name string) bool {
+	if _, ok := i.env.Get(name); ok {
+		return true
+	}
+	if i.evaluator.Builtins == nil {
+		return false
+	}
+	_, ok := i.evaluator.Builtins.Get(name)
+	return ok
+}
+
+func dumpProgram(w io.Writer, program *ast.Program) {
+	for _, stmt := range program.Statements {
+		fmt.Fprintln(w, stmt.String())
+	}
+}
+
+func result(obj object.Object) (object.Object, error) {
+	if err, ok := obj.(*object.Error); ok {
+		return nil, &RuntimeError{Message: err.Message, Err: err.Cause}
+	}
+	return obj, nil
+}
@@ -0,0 +1,331 @@
+package monkey
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/digital-codex/assertions"
+	"github.com/digital-codex/monkey/evaluator"
+	"github.com/digital-codex/monkey/object"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		input    []string
+		expected any
+	}{
+		{[]string{`1 + 2`}, 3.0},
+		{[]string{`let a = 5;`, `a * 2`}, 10.0},
+		{[]string{`let unless = macro(c, a) { quote(if (!(unquote(c))) { unquote(a) }) };`, `unless(false, "ran")`}, "ran"},
+		{[]string{`let a = 1;`}, nil},
+		{[]string{`let f = fn(n) { let sq = macro(x) { quote(unquote(x) * unquote(x)) }; sq(n) };`, `f(3)`}, 9.0},
+		{[]string{`let sum = macro(a, b, c) { quote(fn(x, y, z) { x + y + z }(unquote_splice([a, b, c]))) };`, `sum(1, 2, 3)`}, 6.0},
+		{[]string{`let nothing = macro() { quote(unquote(null)) };`, `nothing() == null`}, true},
+		{[]string{`let as = 1;`, `as`}, 1.0},
+	}
+
+	for i, test := range tests {
+		interpreter := New(nil)
+
+		var evaluated object.Object
+		for _, input := range test.input {
+			var err error
+			evaluated, err = interpreter.Run(input)
+			if err != nil {
+				t.Fatalf("test[%d] - unexpected error: %s", i, err)
+			}
+		}
+
+		actual, err := FromObject(evaluated)
+		if err != nil {
+			t.Fatalf("test[%d] - unexpected error: %s", i, err)
+		}
+		assertions.AssertEquals(t, test.expected, actual, "test["+strconv.Itoa(i)+"] - result wrong")
+	}
+}
+
+func TestRunErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected reflect.Type
+		message  string
+	}{
+		{`let = 5;`, reflect.TypeOf(ParseError{}), ""},
+		{`5 + true`, reflect.TypeOf(RuntimeError{}), "type mismatch: NUMBER + BOOLEAN"},
+		{`let f = fn() { y };`, reflect.TypeOf(ResolveError{}), `Error:1:15: undefined variable "y"`},
+		{`let m = macro(x) { x + 1 }; m(1)`, reflect.TypeOf(MacroError{}), `Error:1:28: error in macro "m": type mismatch: QUOTE + NUMBER`},
+		{`let f = fn(a, b) { a }; f(1)`, reflect.TypeOf(RuntimeError{}), "wrong number of arguments. got=1, want=2"},
+		{`let m = macro(a, b) { a }; m(1)`, reflect.TypeOf(MacroError{}), `Error:1:27: error in macro "m": wrong number of arguments. got=1, want=2`},
+	}
+
+	for i, test := range tests {
+		_, err := New(nil).Run(test.input)
+		assertions.AssertTypeOf(t, test.expected, err, "test["+strconv.Itoa(i)+"] - unexpected type")
+		if test.message != "" {
+			assertions.AssertStringEquals(t, test.message, err.Error(), "test["+strconv.Itoa(i)+"] - err.Error() wrong")
+		}
+	}
+}
+
+func TestRunFile(t *testing.T) {
+	fsys := fstest.MapFS{
+		"scripts/main.mk": &fstest.MapFile{Data: []byte(`import "./lib.mk" as lib; let answer = lib["double"](21);`)},
+		"scripts/lib.mk":  &fstest.MapFile{Data: []byte(`export let double = fn(x) { x * 2 };`)},
+	}
+
+	interpreter := New(fsys)
+	if _, err := interpreter.RunFile("scripts/main.mk"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	answer, ok := interpreter.Get("answer")
+	assertions.AssertBoolEquals(t, true, ok, "answer should be defined")
+	assertions.AssertFloat64Equals(t, 42, answer.(*object.Number).Value, "answer wrong")
+
+	_, err := interpreter.RunFile("scripts/missing.mk")
+	assertions.AssertBoolEquals(t, true, err != nil, "missing file should fail")
+}
+
+func TestCall(t *testing.T) {
+	interpreter := New(nil)
+	if _, err := interpreter.Run(`let greet = fn(name, times) { if (times > 1) { name + "!" } else { name } };`); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	evaluated, err := interpreter.Call("greet", "Monkey", 2)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	assertions.AssertStringEquals(t, "Monkey!", evaluated.Inspect(), "greet() wrong")
+
+	_, err = interpreter.Call("missing")
+	assertions.AssertStringEquals(t, "identifier not found: missing", err.Error(), "missing() error wrong")
+
+	_, err = interpreter.Call("greet", "Monkey", true)
+	assertions.AssertStringEquals(t, "type mismatch: BOOLEAN + NUMBER", err.Error(), "greet() error wrong")
+}
+
+func TestSetGet(t *testing.T) {
+	interpreter := New(nil)
+	if err := interpreter.Set("config", map[string]any{"name": "monkey", "sizes": []int{1, 2, 3}}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	evaluated, err := interpreter.Run(`let size = config["sizes"][2]; config["name"]`)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	assertions.AssertStringEquals(t, "monkey", evaluated.Inspect(), "config[\"name\"] wrong")
+
+	size, ok := interpreter.Get("size")
+	assertions.AssertBoolEquals(t, true, ok, "size should be defined")
+	assertions.AssertFloat64Equals(t, 3, size.(*object.Number).Value, "size wrong")
+	assertions.AssertDeepEquals(t, []string{"config", "size"}, interpreter.Names(), "interpreter.Names() wrong")
+
+	err = interpreter.Set("channel", make(chan int))
+	assertions.AssertBoolEquals(t, true, err != nil, "chan should not convert")
+}
+
+func TestBuiltins(t *testing.T) {
+	interpreter := New(nil)
+	interpreter.Builtins().Register("shout", 1, "shout(s) appends an exclamation mark to s.", func(args ...object.Object) object.Object {
+		return &object.String{Value: args[0].Inspect() + "!"}
+	})
+
+	evaluated, err := interpreter.Run(`shout("hey")`)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	assertions.AssertStringEquals(t, "hey!", evaluated.Inspect(), "shout() wrong")
+
+	interpreter.SetBuiltins(interpreter.Builtins().Scope("len"))
+	_, err = interpreter.Run(`shout("hey")`)
+	assertions.AssertStringEquals(t, `Error:1:0: undefined variable "shout"`, err.Error(), "shout() error wrong")
+}
+
+func TestLimits(t *testing.T) {
+	interpreter := New(nil)
+
+	_, err := interpreter.Run(`let f = fn() { 1 + f() }; f()`)
+	assertions.AssertBoolEquals(t, true, errors.Is(err, evaluator.ErrCallDepth), "unbounded recursion should hit the call depth limit")
+
+	interpreter.SetLimits(evaluator.Limits{Steps: 50})
+	_, err = interpreter.Call("f")
+	assertions.AssertBoolEquals(t, true, errors.Is(err, evaluator.ErrStepLimit), "f() should hit the step limit")
+
+	_, err = interpreter.Run(`let m = macro() { let g = fn() { 1 + g() }; g() }; m()`)
+	assertions.AssertBoolEquals(t, true, errors.Is(err, evaluator.ErrStepLimit), "m() should hit the step limit")
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	interpreter.SetLimits(evaluator.Limits{})
+	<-ctx.Done()
+	_, err = interpreter.RunContext(ctx, `let g = fn(n) { if (n > 0) { g(n - 1) } else { 0 } }; g(1000)`)
+	assertions.AssertBoolEquals(t, true, errors.Is(err, evaluator.ErrCanceled), "run should be canceled")
+	assertions.AssertBoolEquals(t, true, errors.Is(err, context.DeadlineExceeded), "run should report the deadline")
+
+	evaluated, err := interpreter.Run(`1 + 1`)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	assertions.AssertStringEquals(t, "2.000000", evaluated.Inspect(), "interpreter should recover after a stopped run")
+}
+
+func TestMemoryLimit(t *testing.T) {
+	interpreter := New(nil)
+	interpreter.SetLimits(evaluator.Limits{Memory: 4096})
+
+	_, err := interpreter.Run(`let fill = fn(a, n) { if (n > 0) { fill(push(a, n), n - 1) } else { a } }; fill([], 100)`)
+	assertions.AssertBoolEquals(t, true, errors.Is(err, evaluator.ErrMemoryLimit), "fill() should hit the memory limit")
+	assertions.AssertBoolEquals(t, true, interpreter.Stats().Allocated > 4096, "Stats().Allocated should exceed the limit")
+
+	_, err = interpreter.Run(`len(fill([], 10))`)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	stats := interpreter.Stats()
+	assertions.AssertIntEquals(t, 11, stats.Allocations, "Stats().Allocations wrong")
+	assertions.AssertBoolEquals(t, true, stats.Steps > 0, "Stats().Steps wrong")
+}
+
+func TestSetProfile(t *testing.T) {
+	interpreter := New(fstest.MapFS{
+		"lib.mk": &fstest.MapFile{Data: []byte(`export let one = 1;`)},
+	})
+	interpreter.SetProfile(evaluator.PURE)
+
+	_, err := interpreter.Run(`puts("hello")`)
+	assertions.AssertBoolEquals(t, true, errors.Is(err, evaluator.ErrPermission), "puts should be denied")
+
+	_, err = interpreter.Run(`import "lib.mk" as lib;`)
+	assertions.AssertBoolEquals(t, true, errors.Is(err, evaluator.ErrPermission), "import should be denied")
+
+	interpreter.SetProfile(evaluator.FULL)
+	evaluated, err := interpreter.Run(`import "lib.mk" as lib; lib["one"]`)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	assertions.AssertStringEquals(t, "1.000000", evaluated.Inspect(), "evaluated.Inspect() wrong")
+}
+
+func TestStreams(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	interpreter := New(nil)
+	interpreter.SetStdin(strings.NewReader("Monkey\n"))
+	interpreter.SetStdout(&stdout)
+	interpreter.SetStderr(&stderr)
+
+	_, err := interpreter.Run(`let name = readLine(); print("Hello,", name); puts("!"); eprint("done")`)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	assertions.AssertStringEquals(t, "Hello, Monkey!\n", stdout.String(), "stdout wrong")
+	assertions.AssertStringEquals(t, "done\n", stderr.String(), "stderr wrong")
+}
+
+func TestSetHygienic(t *testing.T) {
+	interpreter := New(nil)
+	if _, err := interpreter.Run(`let add = macro(a, b) { quote(fn() { let tmp = unquote(a); tmp + unquote(b) }()) }; let tmp = 10;`); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	evaluated, err := interpreter.Run(`add(1, tmp)`)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	assertions.AssertStringEquals(t, "2.000000", evaluated.Inspect(), "unhygienic add(1, tmp) wrong")
+
+	interpreter.SetHygienic(true)
+	evaluated, err = interpreter.Run(`add(1, tmp)`)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	assertions.AssertStringEquals(t, "11.000000", evaluated.Inspect(), "hygienic add(1, tmp) wrong")
+}
+
+func TestExpand(t *testing.T) {
+	interpreter := New(nil)
+	if _, err := interpreter.Run(`let one = macro() { quote(1) }; let inc = macro(x) { quote(unquote(x) + one()) };`); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	tests := []struct {
+		input    string
+		depth    int
+		expected string
+	}{
+		{`inc(y) * 2`, 0, "((y + 1) * 2)"},
+		{`inc(y) * 2`, 1, "((y + one()) * 2)"},
+		{`let twice = macro(x) { quote(inc(inc(unquote(x)))) }; twice(y)`, 0, "((y + 1) + 1)"},
+		{`let twice = macro(x) { quote(inc(inc(unquote(x)))) }; twice(y)`, 1, "inc(inc(y))"},
+	}
+
+	for i, test := range tests {
+		program, err := interpreter.Expand(test.input, test.depth)
+		if err != nil {
+			t.Fatalf("test[%d] - unexpected error: %s", i, err)
+		}
+		assertions.AssertStringEquals(t, test.expected, program.String(), "test["+strconv.Itoa(i)+"] - program.String() wrong")
+	}
+
+	_, err := interpreter.Run(`twice(1)`)
+	assertions.AssertTypeOf(t, reflect.TypeOf(ResolveError{}), err, "macros defined by Expand should not be kept")
+
+	_, err = interpreter.Expand(`inc(1, 2)`, 0)
+	assertions.AssertTypeOf(t, reflect.TypeOf(MacroError{}), err, "unexpected type")
+}
+
+func TestEnableOptimizer(t *testing.T) {
+	tests := []string{
+		`2 * 3 + 1`,
+		`let a = 5; if (a > 1 + 1) { a * (2 + 2) } else { 0 }`,
+		`let f = fn(n) { if (true) { let m = n + 0; m * 2 } }; f(4)`,
+		`let f = fn() { if (false) { 1 } }; f()`,
+		`let f = fn() { 1; if (false) { 2 } }; f()`,
+		`let f = fn() { let x = 1; }; f()`,
+		`let sq = fn(x) { x * x }; let apply = fn(g, v) { g(v) }; sq(3) + apply(sq, 4)`,
+		`let add = fn(a, b) { a + b }; add(1, true)`,
+		`let neg = fn(b) { !b }; neg(neg(true))`,
+		`let len = fn(x) { 0 }; len("abc")`,
+		`let f = fn(x) { 1 }; f(nope)`,
+		`let f = fn(x, y) { x }; f(1, nope)`,
+		`let counter = fn() { let step = 1; let n = 0; fn() { n + step } }; counter()()`,
+		`if (true) { let a = 2; } a * 3`,
+		`let f = fn(n, acc) { if (n == 0) { acc } else { f(n - 1, acc + 2 * 3) } }; f(100, 0)`,
+		`let f = fn(x) { if (1 < 2) { return x + 1; } return 0; }; f(41)`,
+		`"mon" + "key" == "monkey"`,
+		`{1 + 1: "two", "t" + "hree": 3}[2]`,
+		`[1, 2 * 2, -3][1 + 1]`,
+		`let f = fn() { quote(1 + 2) }; f()`,
+		`let m = macro(x) { quote(unquote(x) * (1 + 1)) }; m(2 + 3)`,
+	}
+
+	for i, input := range tests {
+		var dump bytes.Buffer
+		optimized := New(nil)
+		optimized.EnableOptimizer(&dump)
+
+		expected, expectedErr := New(nil).Run(input)
+		actual, actualErr := optimized.Run(input)
+
+		assertions.AssertStringEquals(t, fmt.Sprint(expectedErr), fmt.Sprint(actualErr), "test["+strconv.Itoa(i)+"] - error wrong")
+		if expectedErr == nil {
+			assertions.AssertStringEquals(t, inspect(expected), inspect(actual), "test["+strconv.Itoa(i)+"] - optimized result wrong")
+		}
+		assertions.AssertBoolEquals(t, true, dump.Len() > 0, "test["+strconv.Itoa(i)+"] - optimized program not dumped")
+	}
+}
+
+func inspect(obj object.Object) string {
+	if obj == nil {
+		return "<nil>"
+	}
+	return obj.Inspect()
+}
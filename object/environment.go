@@ -1,11 +1,18 @@
 package object
 
+import "sort"
+
 /*****************************************************************************
  *                                  TYPES                                    *
  *****************************************************************************/
 
+// Environment binds names to values. Function scopes keep their bindings in
+// slots addressed by the resolver; everything else, including the top-level
+// scope, is bound by name in store.
 type Environment struct {
 	store map[string]Object
+	slots []Object
+	names []string // the names of slots
 	outer *Environment
 }
 
@@ -24,25 +31,112 @@ func NewEnclosedEnvironment(outer *Environment) *Environment {
 	return env
 }
 
+// ExtendEnvironment returns the scope of a call to obj with args: one slot for
+// each of its locals, the first of which hold the arguments.
 func ExtendEnvironment(obj Closure, args []Object) *Environment {
-	env := NewEnclosedEnvironment(obj.env())
+	params := obj.parameters()
+
+	names := obj.locals()
+	if names == nil {
+		names = make([]string, len(params))
+		for i, param := range params {
+			names[i] = param.Value
+		}
+	}
 
-	for i, param := range obj.parameters() {
-		env.Set(param.Value, args[i])
+	env := &Environment{slots: make([]Object, len(names)), names: names, outer: obj.env()}
+	for i := range params {
+		if i < len(args) {
+			env.slots[i] = args[i]
+		}
 	}
 
 	return env
 }
 
 func (e *Environment) Get(name string) (Object, bool) {
-	obj, ok := e.store[name]
-	if !ok && e.outer != nil {
-		obj, ok = e.outer.Get(name)
+	for env := e; env != nil; env = env.outer {
+		if obj, ok := env.store[name]; ok {
+			return obj, true
+		}
+		for i, n := range env.names {
+			if n == name && env.slots[i] != nil {
+				return env.slots[i], true
+			}
+		}
 	}
-	return obj, ok
+	return nil, false
 }
 
 func (e *Environment) Set(name string, val Object) Object {
+	for i, n := range e.names {
+		if n == name {
+			e.slots[i] = val
+			return val
+		}
+	}
+	if e.store == nil {
+		e.store = make(map[string]Object)
+	}
 	e.store[name] = val
 	return val
 }
+
+// GetAt returns the value in slot of the scope depth levels out of e. It
+// reports false while the slot is unbound.
+func (e *Environment) GetAt(depth int, slot int) (Object, bool) {
+	env := e.ancestor(depth)
+	if env == nil || slot >= len(env.slots) || env.slots[slot] == nil {
+		return nil, false
+	}
+	return env.slots[slot], true
+}
+
+func (e *Environment) SetAt(depth int, slot int, val Object) Object {
+	e.ancestor(depth).slots[slot] = val
+	return val
+}
+
+// Global returns the top-level scope e is enclosed in.
+func (e *Environment) Global() *Environment {
+	env := e
+	for env.outer != nil {
+		env = env.outer
+	}
+	return env
+}
+
+// Names returns the names bound in e and the scopes it is enclosed in, in
+// sorted order.
+func (e *Environment) Names() []string {
+	seen := make(map[string]bool)
+	for env := e; env != nil; env = env.outer {
+		for name := range env.store {
+			seen[name] = true
+		}
+		for i, name := range env.names {
+			if env.slots[i] != nil {
+				seen[name] = true
+			}
+		}
+	}
+
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+/*****************************************************************************
+ *                             PRIVATE FUNCTIONS                             *
+ *****************************************************************************/
+
+func (e *Environment) ancestor(depth int) *Environment {
+	env := e
+	for ; depth > 0 && env != nil; depth-- {
+		env = env.outer
+	}
+	return env
+}
@@ -0,0 +1,22 @@
+package object
+
+import (
+	"github.com/digital-codex/assertions"
+	"testing"
+)
+
+func TestNames(t *testing.T) {
+	global := NewEnvironment()
+	global.Set("y", &Number{Value: 1})
+	global.Set("x", &Number{Value: 2})
+
+	fn := &Function{Env: global, Locals: []string{"a", "b"}}
+	local := ExtendEnvironment(fn, nil)
+	local.SetAt(0, 1, &Number{Value: 3})
+	enclosed := NewEnclosedEnvironment(local)
+	enclosed.Set("x", &Number{Value: 4})
+
+	assertions.AssertDeepEquals(t, []string{"x", "y"}, global.Names(), "global.Names() wrong")
+	assertions.AssertDeepEquals(t, []string{"b", "x", "y"}, enclosed.Names(), "enclosed.Names() wrong")
+	assertions.AssertDeepEquals(t, []string{}, NewEnvironment().Names(), "NewEnvironment().Names() wrong")
+}
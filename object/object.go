@@ -6,6 +6,7 @@ import (
 	"github.com/digital-codex/monkey/ast"
 	"hash/fnv"
 	"math/rand"
+	"sort"
 	"strings"
 )
 
@@ -21,6 +22,7 @@ type Object interface {
 type Closure interface {
 	env() *Environment
 	parameters() []*ast.Identifier
+	locals() []string
 }
 
 type Hashable interface {
@@ -47,6 +49,9 @@ const (
 	HASH
 	QUOTE
 	MACRO
+	MODULE
+	TAIL_CALL
+	SYMBOL
 )
 
 var objects = [...]string{
@@ -63,6 +68,9 @@ var objects = [...]string{
 	HASH:         "HASH",
 	QUOTE:        "QUOTE",
 	MACRO:        "MACRO",
+	MODULE:       "MODULE",
+	TAIL_CALL:    "TAIL_CALL",
+	SYMBOL:       "SYMBOL",
 }
 
 func (t Type) String() string {
@@ -83,19 +91,35 @@ type ReturnValue struct {
 	Value Object
 }
 
+// TailCall is a call in tail position, left for the caller to perform so the
+// evaluator does not grow the Go stack.
+type TailCall struct {
+	Function  Object
+	Arguments []Object
+}
+
 type Error struct {
 	Message string
+	Cause   error // the Go error that stopped evaluation, if any
 }
 
 type Function struct {
 	Parameters []*ast.Identifier
 	Body       *ast.Block
 	Env        *Environment
+	Locals     []string
 }
 
+// VARIADIC is the Arity of a Builtin accepting any number of arguments.
+const VARIADIC = -1
+
 type BuiltinFunction func(args ...Object) Object
 type Builtin struct {
-	Fn BuiltinFunction
+	Name      string
+	Arity     int // exact number of arguments, or VARIADIC
+	Doc       string
+	Fn        BuiltinFunction
+	Allocates bool // whether Fn returns new objects, counted against the memory limit
 }
 
 type String struct {
@@ -124,12 +148,23 @@ type Quote struct {
 	Node ast.Node
 }
 
+// Symbol is an identifier made by gensym. Inside quote, an identifier bound
+// to a Symbol stands for the identifier Name, which no source code can spell.
+type Symbol struct {
+	Name string
+}
+
 type Macro struct {
 	Parameters []*ast.Identifier
 	Body       *ast.Block
 	Env        *Environment
 }
 
+type Module struct {
+	Name    string
+	Exports map[string]Object
+}
+
 /*****************************************************************************
  *                                OBJECTS                                    *
  *****************************************************************************/
@@ -146,6 +181,9 @@ func (n *Null) Type() Type {
 func (rv *ReturnValue) Type() Type {
 	return RETURN_VALUE
 }
+func (tc *TailCall) Type() Type {
+	return TAIL_CALL
+}
 func (e *Error) Type() Type {
 	return ERROR
 }
@@ -167,9 +205,15 @@ func (h *Hash) Type() Type {
 func (q *Quote) Type() Type {
 	return QUOTE
 }
+func (s *Symbol) Type() Type {
+	return SYMBOL
+}
 func (m *Macro) Type() Type {
 	return MACRO
 }
+func (m *Module) Type() Type {
+	return MODULE
+}
 
 func (i *Number) Inspect() string {
 	return fmt.Sprintf("%f", i.Value)
@@ -183,6 +227,9 @@ func (n *Null) Inspect() string {
 func (rv *ReturnValue) Inspect() string {
 	return rv.Value.Inspect()
 }
+func (tc *TailCall) Inspect() string {
+	return "tail call of " + tc.Function.Inspect()
+}
 func (e *Error) Inspect() string {
 	return "Error: " + e.Message
 }
@@ -238,7 +285,10 @@ func (h *Hash) Inspect() string {
 	return out.String()
 }
 func (q *Quote) Inspect() string {
-	return "QUOTE(" + q.Node.String() + ")"
+	return "QUOTE(" + ast.RenameSymbols(q.Node).String() + ")"
+}
+func (s *Symbol) Inspect() string {
+	return s.Name
 }
 func (m *Macro) Inspect() string {
 	var out bytes.Buffer
@@ -256,6 +306,21 @@ func (m *Macro) Inspect() string {
 
 	return out.String()
 }
+func (m *Module) Inspect() string {
+	var out bytes.Buffer
+
+	var names []string
+	for name := range m.Exports {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	out.WriteString("module")
+	out.WriteString("(" + m.Name + ")")
+	out.WriteString("{" + strings.Join(names, ", ") + "}")
+
+	return out.String()
+}
 
 /*****************************************************************************
  *                               CLOSURE                                     *
@@ -275,6 +340,13 @@ func (m *Macro) parameters() []*ast.Identifier {
 	return m.Parameters
 }
 
+func (f *Function) locals() []string {
+	return f.Locals
+}
+func (m *Macro) locals() []string {
+	return nil
+}
+
 /*****************************************************************************
  *                               HASHABLE                                    *
  *****************************************************************************/
@@ -0,0 +1,513 @@
+package optimizer
+
+import (
+	"github.com/digital-codex/monkey/ast"
+	"github.com/digital-codex/monkey/token"
+	"math"
+	"strconv"
+)
+
+/*****************************************************************************
+ *                                  TYPES                                    *
+ *****************************************************************************/
+
+// optimizer holds what the passes know about the program being optimized.
+// Nodes inside calls to quote are data rather than code and are never
+// touched.
+type optimizer struct {
+	quoted map[ast.Node]bool
+}
+
+/*****************************************************************************
+ *                              PUBLIC FUNCTIONS                             *
+ *****************************************************************************/
+
+// Optimize rewrites program in place into a program with the same behavior
+// that does less work: calls to trivial functions are inlined, constant
+// expressions folded, branches on constant conditions eliminated and unused
+// pure let declarations inside functions removed. It expects macros to be
+// expanded and assumes that top-level functions are not redeclared by later
+// runs in the same environment.
+func Optimize(program *ast.Program) *ast.Program {
+	o := &optimizer{quoted: quotedNodes(program)}
+
+	o.inline(program)
+	o.fold(program)
+	o.prune(program)
+	// pruning may leave constant expressions behind, e.g. (if (true) { 1 }) + 2
+	o.fold(program)
+	o.removeUnusedDeclarations(program)
+
+	return program
+}
+
+/*****************************************************************************
+ *                             PRIVATE FUNCTIONS                             *
+ *****************************************************************************/
+
+/*
+ * Constant folding
+ */
+
+func (o *optimizer) fold(program *ast.Program) {
+	ast.Modify(program, func(node ast.Node) ast.Node {
+		if o.quoted[node] {
+			return node
+		}
+
+		switch node := node.(type) {
+		case *ast.GroupedExpression:
+			if isLiteral(node.Expression) {
+				return node.Expression
+			}
+		case *ast.PrefixExpression:
+			if folded := foldPrefix(node); folded != nil {
+				return folded
+			}
+		case *ast.InfixExpression:
+			if folded := foldInfix(node); folded != nil {
+				return folded
+			}
+		}
+		return node
+	})
+}
+
+func foldPrefix(node *ast.PrefixExpression) ast.Expression {
+	switch node.Operator {
+	case "-":
+		if right, ok := node.Right.(*ast.NumberLiteral); ok {
+			return makeNumber(node.Token, -right.Value)
+		}
+	case "!":
+		if isLiteral(node.Right) {
+			return makeBoolean(node.Token, !isTruthy(node.Right))
+		}
+	}
+	return nil
+}
+
+func foldInfix(node *ast.InfixExpression) ast.Expression {
+	switch left := node.Left.(type) {
+	case *ast.NumberLiteral:
+		right, ok := node.Right.(*ast.NumberLiteral)
+		if !ok {
+			return nil
+		}
+		switch node.Operator {
+		case "+":
+			return makeNumber(node.Token, left.Value+right.Value)
+		case "-":
+			return makeNumber(node.Token, left.Value-right.Value)
+		case "*":
+			return makeNumber(node.Token, left.Value*right.Value)
+		case "/":
+			return makeNumber(node.Token, left.Value/right.Value)
+		case "<":
+			return makeBoolean(node.Token, left.Value < right.Value)
+		case ">":
+			return makeBoolean(node.Token, left.Value > right.Value)
+		case "==":
+			return makeBoolean(node.Token, left.Value == right.Value)
+		case "!=":
+			return makeBoolean(node.Token, left.Value != right.Value)
+		}
+	case *ast.StringLiteral:
+		// strings compare by identity, so only concatenation folds
+		right, ok := node.Right.(*ast.StringLiteral)
+		if ok && node.Operator == "+" {
+			return makeString(node.Token, left.Value+right.Value)
+		}
+	case *ast.Boolean:
+		right, ok := node.Right.(*ast.Boolean)
+		if !ok {
+			return nil
+		}
+		switch node.Operator {
+		case "==":
+			return makeBoolean(node.Token, left.Value == right.Value)
+		case "!=":
+			return makeBoolean(node.Token, left.Value != right.Value)
+		}
+	}
+	return nil
+}
+
+/*
+ * Dead branch elimination
+ */
+
+// prune replaces if expressions on a constant condition by the branch taken.
+// As an expression this is only possible when that branch is a single
+// expression; as a statement its statements are spliced into the enclosing
+// list, since blocks do not open scopes.
+func (o *optimizer) prune(program *ast.Program) {
+	ast.Modify(program, func(node ast.Node) ast.Node {
+		if o.quoted[node] {
+			return node
+		}
+
+		switch node := node.(type) {
+		case *ast.IfExpression:
+			branch, ok := takenBranch(node)
+			if ok && branch != nil && len(branch.Statements) == 1 {
+				if stmt, ok := branch.Statements[0].(*ast.ExpressionStatement); ok {
+					return stmt.Expression
+				}
+			}
+		case *ast.Block:
+			node.Statements = pruneStatements(node.Statements)
+		case *ast.Program:
+			node.Statements = pruneStatements(node.Statements)
+		}
+		return node
+	})
+}
+
+func pruneStatements(stmts []ast.Statement) []ast.Statement {
+	pruned := make([]ast.Statement, 0, len(stmts))
+	for i, stmt := range stmts {
+		es, ok := stmt.(*ast.ExpressionStatement)
+		if !ok {
+			pruned = append(pruned, stmt)
+			continue
+		}
+		ie, ok := es.Expression.(*ast.IfExpression)
+		if !ok {
+			pruned = append(pruned, stmt)
+			continue
+		}
+		branch, ok := takenBranch(ie)
+		if !ok {
+			pruned = append(pruned, stmt)
+			continue
+		}
+
+		// the value of the last statement is the value of the list, which
+		// is null rather than nothing when no branch is taken
+		last := i == len(stmts)-1
+		if last && (branch == nil || len(branch.Statements) == 0) {
+			pruned = append(pruned, stmt)
+			continue
+		}
+		if branch != nil {
+			pruned = append(pruned, branch.Statements...)
+		}
+	}
+	return pruned
+}
+
+// takenBranch returns the branch node takes when its condition is constant,
+// which is nil when the condition is false and there is no alternative.
+func takenBranch(node *ast.IfExpression) (*ast.Block, bool) {
+	if !isLiteral(node.Condition) {
+		return nil, false
+	}
+	if isTruthy(node.Condition) {
+		return node.Consequence, true
+	}
+	return node.Alternative, true
+}
+
+/*
+ * Unused declarations
+ */
+
+// removeUnusedDeclarations removes the let declarations inside functions that
+// bind a pure value to a name which is never referenced. Top-level
+// declarations are kept, since later runs and the host may refer to them.
+func (o *optimizer) removeUnusedDeclarations(program *ast.Program) {
+	referenced := referencedNames(program)
+	bodies := functionBlocks(program)
+
+	ast.Modify(program, func(node ast.Node) ast.Node {
+		block, ok := node.(*ast.Block)
+		if !ok || !bodies[block] || o.quoted[node] {
+			return node
+		}
+
+		stmts := make([]ast.Statement, 0, len(block.Statements))
+		for i, stmt := range block.Statements {
+			decl, ok := stmt.(*ast.LetDeclaration)
+			last := i == len(block.Statements)-1
+			if ok && !last && !referenced[decl.Name.Value] && isPure(decl.Value) {
+				continue
+			}
+			stmts = append(stmts, stmt)
+		}
+		block.Statements = stmts
+
+		return block
+	})
+}
+
+/*
+ * Inlining
+ */
+
+// inline replaces calls to trivial functions by their body. A function is
+// trivial when it is declared once, by a top-level let, and its body is a
+// single expression of operators, literals and its parameters, using every
+// parameter so that no argument is dropped unevaluated. Only the calls
+// in the statements after the declaration whose arguments are literals or
+// identifiers are inlined, so no argument is evaluated a different number of
+// times.
+func (o *optimizer) inline(program *ast.Program) {
+	declared := declarationCounts(program)
+	trivial := make(map[string]*ast.FunctionLiteral)
+
+	for i, stmt := range program.Statements {
+		if len(trivial) > 0 {
+			program.Statements[i] = ast.Modify(stmt, func(node ast.Node) ast.Node {
+				if ce, ok := node.(*ast.CallExpression); ok && !o.quoted[node] {
+					return inlineCall(ce, trivial)
+				}
+				return node
+			}).(ast.Statement)
+		}
+
+		decl, ok := stmt.(*ast.LetDeclaration)
+		if !ok || declared[decl.Name.Value] != 1 {
+			continue
+		}
+		if fn, ok := decl.Value.(*ast.FunctionLiteral); ok && isTrivial(fn) {
+			trivial[decl.Name.Value] = fn
+		}
+	}
+}
+
+func inlineCall(ce *ast.CallExpression, trivial map[string]*ast.FunctionLiteral) ast.Expression {
+	ident, ok := ce.Function.(*ast.Identifier)
+	if !ok {
+		return ce
+	}
+	fn, ok := trivial[ident.Value]
+	if !ok || len(fn.Parameters) != len(ce.Argument) {
+		return ce
+	}
+
+	args := make(map[string]ast.Expression)
+	for i, arg := range ce.Argument {
+		if !isLiteral(arg) {
+			if _, ok := arg.(*ast.Identifier); !ok {
+				return ce
+			}
+		}
+		args[fn.Parameters[i].Value] = arg
+	}
+
+	body := fn.Body.Statements[0].(*ast.ExpressionStatement).Expression
+	return &ast.GroupedExpression{Token: ce.Token, Expression: substitute(body, args)}
+}
+
+func isTrivial(fn *ast.FunctionLiteral) bool {
+	params := make(map[string]bool)
+	for _, param := range fn.Parameters {
+		if params[param.Value] {
+			return false
+		}
+		params[param.Value] = true
+	}
+
+	if len(fn.Body.Statements) != 1 {
+		return false
+	}
+	stmt, ok := fn.Body.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		return false
+	}
+
+	used := make(map[string]bool)
+	var trivial func(ast.Expression) bool
+	trivial = func(node ast.Expression) bool {
+		switch node := node.(type) {
+		case *ast.NumberLiteral, *ast.StringLiteral, *ast.Boolean:
+			return true
+		case *ast.Identifier:
+			used[node.Value] = true
+			return params[node.Value]
+		case *ast.PrefixExpression:
+			return trivial(node.Right)
+		case *ast.InfixExpression:
+			return trivial(node.Left) && trivial(node.Right)
+		case *ast.GroupedExpression:
+			return trivial(node.Expression)
+		default:
+			return false
+		}
+	}
+
+	return trivial(stmt.Expression) && len(used) == len(params)
+}
+
+// substitute returns a copy of the trivial expression node with its
+// parameters replaced by args.
+func substitute(node ast.Expression, args map[string]ast.Expression) ast.Expression {
+	switch node := node.(type) {
+	case *ast.Identifier:
+		return ast.Clone(args[node.Value]).(ast.Expression)
+	case *ast.PrefixExpression:
+		return &ast.PrefixExpression{Token: node.Token, Operator: node.Operator, Right: substitute(node.Right, args)}
+	case *ast.InfixExpression:
+		return &ast.InfixExpression{Token: node.Token, Left: substitute(node.Left, args), Operator: node.Operator, Right: substitute(node.Right, args)}
+	case *ast.GroupedExpression:
+		return &ast.GroupedExpression{Token: node.Token, Expression: substitute(node.Expression, args)}
+	default:
+		return ast.Clone(node).(ast.Expression)
+	}
+}
+
+/*
+ * Analysis
+ */
+
+// quotedNodes returns the nodes inside calls to quote, including the calls.
+func quotedNodes(program *ast.Program) map[ast.Node]bool {
+	quoted := make(map[ast.Node]bool)
+	mark := func(node ast.Node) ast.Node {
+		quoted[node] = true
+		return node
+	}
+
+	ast.Modify(program, func(node ast.Node) ast.Node {
+		if ce, ok := node.(*ast.CallExpression); ok && ce.Function.TokenLexeme() == "quote" {
+			ast.Modify(ce, mark)
+		}
+		return node
+	})
+
+	return quoted
+}
+
+// functionBlocks returns the blocks that belong to the body of a function.
+func functionBlocks(program *ast.Program) map[*ast.Block]bool {
+	blocks := make(map[*ast.Block]bool)
+	mark := func(node ast.Node) ast.Node {
+		if block, ok := node.(*ast.Block); ok {
+			blocks[block] = true
+		}
+		return node
+	}
+
+	ast.Modify(program, func(node ast.Node) ast.Node {
+		if fn, ok := node.(*ast.FunctionLiteral); ok {
+			ast.Modify(fn.Body, mark)
+		}
+		return node
+	})
+
+	return blocks
+}
+
+// referencedNames returns the names of every identifier that is not itself
+// being declared, quoted code included.
+func referencedNames(program *ast.Program) map[string]bool {
+	declarations := make(map[*ast.Identifier]bool)
+	ast.Modify(program, func(node ast.Node) ast.Node {
+		switch node := node.(type) {
+		case *ast.LetDeclaration:
+			declarations[node.Name] = true
+		case *ast.FunctionLiteral:
+			for _, param := range node.Parameters {
+				declarations[param] = true
+			}
+		}
+		return node
+	})
+
+	referenced := make(map[string]bool)
+	ast.Modify(program, func(node ast.Node) ast.Node {
+		if ident, ok := node.(*ast.Identifier); ok && !declarations[ident] {
+			referenced[ident.Value] = true
+		}
+		return node
+	})
+
+	return referenced
+}
+
+// declarationCounts returns how many times each name is declared by a let,
+// an import or a parameter.
+func declarationCounts(program *ast.Program) map[string]int {
+	counts := make(map[string]int)
+	ast.Modify(program, func(node ast.Node) ast.Node {
+		switch node := node.(type) {
+		case *ast.LetDeclaration:
+			counts[node.Name.Value]++
+		case *ast.ImportStatement:
+			counts[node.Name.Value]++
+		case *ast.FunctionLiteral:
+			for _, param := range node.Parameters {
+				counts[param.Value]++
+			}
+		case *ast.MacroLiteral:
+			for _, param := range node.Parameters {
+				counts[param.Value]++
+			}
+		}
+		return node
+	})
+	return counts
+}
+
+func isLiteral(node ast.Node) bool {
+	switch node.(type) {
+	case *ast.NumberLiteral, *ast.StringLiteral, *ast.Boolean:
+		return true
+	default:
+		return false
+	}
+}
+
+// isPure reports whether evaluating node can neither fail nor have an effect.
+func isPure(node ast.Expression) bool {
+	switch node := node.(type) {
+	case *ast.NumberLiteral, *ast.StringLiteral, *ast.Boolean, *ast.FunctionLiteral:
+		return true
+	case *ast.GroupedExpression:
+		return isPure(node.Expression)
+	case *ast.ArrayLiteral:
+		for _, elem := range node.Elements {
+			if !isPure(elem) {
+				return false
+			}
+		}
+		return true
+	case *ast.HashLiteral:
+		for key, val := range node.Pairs {
+			if !isLiteral(key) || !isPure(val) {
+				return false
+			}
+		}
+		return true
+	default:
+		return false
+	}
+}
+
+// isTruthy mirrors the truthiness of the evaluator for a literal node.
+func isTruthy(node ast.Node) bool {
+	if b, ok := node.(*ast.Boolean); ok {
+		return b.Value
+	}
+	return true
+}
+
+func makeNumber(t token.Token, value float64) *ast.NumberLiteral {
+	lexeme := strconv.FormatFloat(value, 'f', -1, 64)
+	if math.IsInf(value, 0) || math.IsNaN(value) {
+		lexeme = strconv.FormatFloat(value, 'g', -1, 64)
+	}
+	return &ast.NumberLiteral{Token: token.Token{Type: token.NUMBER, Start: t.Start, Line: t.Line, Lexeme: lexeme}, Value: value}
+}
+
+func makeString(t token.Token, value string) *ast.StringLiteral {
+	return &ast.StringLiteral{Token: token.Token{Type: token.STRING, Start: t.Start, Line: t.Line, Lexeme: value}, Value: value}
+}
+
+func makeBoolean(t token.Token, value bool) *ast.Boolean {
+	if value {
+		return &ast.Boolean{Token: token.Token{Type: token.TRUE, Start: t.Start, Line: t.Line, Lexeme: "true"}, Value: true}
+	}
+	return &ast.Boolean{Token: token.Token{Type: token.FALSE, Start: t.Start, Line: t.Line, Lexeme: "false"}, Value: false}
+}
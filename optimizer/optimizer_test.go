@@ -0,0 +1,88 @@
+package optimizer
+
+import (
+	"github.com/digital-codex/assertions"
+	"github.com/digital-codex/monkey/ast"
+	"github.com/digital-codex/monkey/parser"
+	"strconv"
+	"testing"
+)
+
+func TestOptimize(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		// constant folding
+		{`2 * 3 + 1`, `7`},
+		{`-(4 / 2)`, `-2`},
+		{`1 < 2 == true`, `true`},
+		{`!5`, `false`},
+		{`"foo" + "bar"`, `foobar`},
+		{`"a" == "a"`, `(a == a)`},
+		{`let x = 2; x * (3 + 4)`, `let x = 2;(x * 7)`},
+		{`1 / 0`, `+Inf`},
+		// dead branch elimination
+		{`if (true) { 1 } else { 2 }`, `1`},
+		{`if (1 > 2) { 1 } else { 2 }`, `2`},
+		{`if (false) { 1 }`, `if(false){1}`},
+		{`if (false) { 1 }; 2`, `2`},
+		{`if (true) { let a = 1; a }; 3`, `let a = 1;a3`},
+		{`let f = fn(x) { if (true) { return x; } x + 1 }`, `let f = fn(x){return x;(x + 1)};`},
+		{`(if (true) { 1 }) + 2`, `3`},
+		// unused pure declarations
+		{`let f = fn() { let a = 1; let b = [2, "c"]; 3 }`, `let f = fn(){3};`},
+		{`let f = fn() { let a = 1; a }`, `let f = fn(){let a = 1;a};`},
+		{`let f = fn() { let a = g(); 3 }`, `let f = fn(){let a = g();3};`},
+		{`let f = fn() { 3; let a = 1; }`, `let f = fn(){3let a = 1;};`},
+		{`let unused = 1;`, `let unused = 1;`},
+		// inlining
+		{`let double = fn(x) { x * 2 }; double(21)`, `let double = fn(x){(x * 2)};42`},
+		{`let add = fn(a, b) { a + b }; let y = 1; add(y, 2)`, `let add = fn(a, b){(a + b)};let y = 1;(y + 2)`},
+		{`let add = fn(a, b) { a + b }; add(1)`, `let add = fn(a, b){(a + b)};add(1)`},
+		{`let double = fn(x) { x * 2 }; double(f())`, `let double = fn(x){(x * 2)};double(f())`},
+		{`double(1); let double = fn(x) { x * 2 };`, `double(1)let double = fn(x){(x * 2)};`},
+		{`let f = fn() { let len = fn(x) { 0 }; 1 }; len("abc")`, `let f = fn(){let len = fn(x){0};1};len(abc)`},
+		{`let g = fn(x) { h(x) }; g(1)`, `let g = fn(x){h(x)};g(1)`},
+		{`let one = fn(x) { 1 }; one(y)`, `let one = fn(x){1};one(y)`},
+		// quoted code is data
+		{`quote(1 + 2)`, `quote((1 + 2))`},
+		{`quote(if (true) { 1 })`, `quote(if(true){1})`},
+	}
+
+	for i, test := range tests {
+		program := Optimize(parse(t, test.input))
+		assertions.AssertStringEquals(t, test.expected, program.String(), "test["+strconv.Itoa(i)+"] - program.String() wrong")
+	}
+}
+
+func TestOptimizeEqual(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`2 * 3 + 1`, `7`},
+		{`let x = 2; x * (3 + 4)`, `let x = 2; x * 7`},
+		{`if (1 > 2) { 1 } else { 2 }`, `2`},
+		{`let f = fn() { let a = 1; 3 }`, `let f = fn() { 3 };`},
+		{`let double = fn(x) { x * 2 }; double(21)`, `let double = fn(x) { x * 2 }; 42`},
+		{`let add = fn(a, b) { a + b }; let y = 1; add(y, 2)`, `let add = fn(a, b) { a + b }; let y = 1; (y + 2)`},
+	}
+
+	for i, test := range tests {
+		program := Optimize(parse(t, test.input))
+		expected := parse(t, test.expected)
+		if !ast.Equal(expected, program) {
+			t.Errorf("test[%d] - program wrong: expected=%s, actual=%s", i, expected, program)
+		}
+	}
+}
+
+func parse(t *testing.T, input string) *ast.Program {
+	p := parser.New(input)
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser has %d errors: %v", len(p.Errors()), p.Errors())
+	}
+	return program
+}
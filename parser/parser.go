@@ -104,10 +104,13 @@ func New(input string) *Parser {
 	p.registerRule(token.LET, nil, nil, NONE)
 	p.registerRule(token.TRUE, p.parseBoolean, nil, NONE)
 	p.registerRule(token.FALSE, p.parseBoolean, nil, NONE)
+	p.registerRule(token.NULL, p.parseNull, nil, NONE)
 	p.registerRule(token.IF, p.parseIfExpression, nil, NONE)
 	p.registerRule(token.ELSE, nil, nil, NONE)
 	p.registerRule(token.RETURN, nil, nil, NONE)
 	p.registerRule(token.MACRO, p.parseMacroLiteral, nil, NONE)
+	p.registerRule(token.EXPORT, nil, nil, NONE)
+	p.registerRule(token.IMPORT, nil, nil, NONE)
 
 	p.registerRule(token.ILLEGAL, nil, nil, NONE)
 
@@ -119,7 +122,7 @@ func (p *Parser) ParseProgram() *ast.Program {
 	program.Statements = []ast.Statement{}
 
 	for p.current.Type != token.EOF {
-		stmt := p.parseDeclaration()
+		stmt := p.parseTopLevelDeclaration()
 		if stmt != nil {
 			program.Statements = append(program.Statements, stmt)
 		}
@@ -137,6 +140,61 @@ func (p *Parser) Errors() []error {
  *                             PRIVATE FUNCTIONS                             *
  *****************************************************************************/
 
+func (p *Parser) parseTopLevelDeclaration() ast.Statement {
+	switch p.current.Type {
+	case token.IMPORT:
+		return p.parseImportStatement()
+	case token.EXPORT:
+		return p.parseExportDeclaration()
+	default:
+		return p.parseDeclaration()
+	}
+}
+
+func (p *Parser) parseImportStatement() *ast.ImportStatement {
+	stmt := &ast.ImportStatement{Token: p.current}
+
+	if !p.expect(token.STRING) {
+		return nil
+	}
+
+	stmt.Path = &ast.StringLiteral{Token: p.current, Value: p.current.Lexeme}
+
+	// as is not a keyword, so it can still name a variable elsewhere
+	if !p.peekTokenIs(token.IDENT) || p.peek.Lexeme != "as" {
+		p.error(UNEXPECTED_TOKEN)
+		return nil
+	}
+	p.next()
+
+	if !p.expect(token.IDENT) {
+		return nil
+	}
+
+	stmt.Name = &ast.Identifier{Token: p.current, Value: p.current.Lexeme}
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.next()
+	}
+
+	return stmt
+}
+
+func (p *Parser) parseExportDeclaration() *ast.ExportDeclaration {
+	stmt := &ast.ExportDeclaration{Token: p.current}
+
+	if !p.expect(token.LET) {
+		return nil
+	}
+
+	stmt.Declaration = p.parseLetDeclaration()
+	if stmt.Declaration == nil {
+		return nil
+	}
+
+	return stmt
+}
+
 func (p *Parser) parseDeclaration() ast.Statement {
 	switch p.current.Type {
 	case token.LET:
@@ -218,6 +276,9 @@ func (p *Parser) parseBlock() *ast.Block {
 		}
 		p.next()
 	}
+	if p.currentTokenIs(token.RBRACE) {
+		block.Closing = p.current
+	}
 
 	return block
 }
@@ -289,6 +350,7 @@ func (p *Parser) parseGroupedExpression() ast.Expression {
 	if !p.expect(token.RPAREN) {
 		return nil
 	}
+	expr.Closing = p.current
 
 	return expr
 }
@@ -297,6 +359,10 @@ func (p *Parser) parseBoolean() ast.Expression {
 	return &ast.Boolean{Token: p.current, Value: p.currentTokenIs(token.TRUE)}
 }
 
+func (p *Parser) parseNull() ast.Expression {
+	return &ast.Null{Token: p.current}
+}
+
 func (p *Parser) parseIfExpression() ast.Expression {
 	expr := &ast.IfExpression{Token: p.current}
 
@@ -349,6 +415,7 @@ func (p *Parser) parseFunctionLiteral() ast.Expression {
 func (p *Parser) parseCallExpression(function ast.Expression) ast.Expression {
 	expr := &ast.CallExpression{Token: p.current, Function: function}
 	expr.Argument = p.parseExpressions(token.RPAREN)
+	expr.Closing = p.current
 	return expr
 }
 
@@ -359,6 +426,7 @@ func (p *Parser) parseStringLiteral() ast.Expression {
 func (p *Parser) parseArrayLiteral() ast.Expression {
 	expr := &ast.ArrayLiteral{Token: p.current}
 	expr.Elements = p.parseExpressions(token.RBRACKET)
+	expr.Closing = p.current
 	return expr
 }
 
@@ -371,6 +439,7 @@ func (p *Parser) parseIndexExpression(left ast.Expression) ast.Expression {
 	if !p.expect(token.RBRACKET) {
 		return nil
 	}
+	expr.Closing = p.current
 
 	return expr
 }
@@ -400,6 +469,7 @@ func (p *Parser) parseHashLiteral() ast.Expression {
 	if !p.expect(token.RBRACE) {
 		return nil
 	}
+	expr.Closing = p.current
 
 	return expr
 }
@@ -81,6 +81,114 @@ func TestReturnStatement(t *testing.T) {
 	}
 }
 
+func TestImportStatement(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected struct {
+			path  string
+			ident string
+		}
+	}{
+		{
+			input: `import "math.mk" as math;`,
+			expected: struct {
+				path  string
+				ident string
+			}{
+				"math.mk",
+				"math",
+			},
+		},
+		{
+			input: `import "./lib/strings.mk" as str`,
+			expected: struct {
+				path  string
+				ident string
+			}{
+				"./lib/strings.mk",
+				"str",
+			},
+		},
+		{
+			input: `import "as.mk" as as;`,
+			expected: struct {
+				path  string
+				ident string
+			}{
+				"as.mk",
+				"as",
+			},
+		},
+	}
+
+	for i, test := range tests {
+		p := New(test.input)
+		program := p.ParseProgram()
+
+		checkParserErrors(t, p)
+		testProgram(t, i, program)
+
+		testStatement(program.Statements[0])(t, i, program.Statements[0], test.expected.path, test.expected.ident)
+	}
+}
+
+func TestExportDeclaration(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected struct {
+			ident string
+			value any
+		}
+	}{
+		{
+			input: `export let x = 5;`,
+			expected: struct {
+				ident string
+				value any
+			}{
+				"x",
+				5,
+			},
+		},
+		{
+			input: `export let y = true`,
+			expected: struct {
+				ident string
+				value any
+			}{
+				"y",
+				true,
+			},
+		},
+	}
+
+	for i, test := range tests {
+		p := New(test.input)
+		program := p.ParseProgram()
+
+		checkParserErrors(t, p)
+		testProgram(t, i, program)
+
+		testStatement(program.Statements[0])(t, i, program.Statements[0], test.expected.ident, test.expected.value)
+	}
+}
+
+func TestTopLevelOnlyDeclarations(t *testing.T) {
+	tests := []string{
+		`fn() { import "math.mk" as math; }`,
+		`if (true) { export let x = 5; }`,
+		`import "math.mk" math;`,
+		`import "math.mk" is math;`,
+	}
+
+	for i, test := range tests {
+		p := New(test)
+		p.ParseProgram()
+
+		assertions.AssertBoolEquals(t, true, len(p.Errors()) > 0, "test["+strconv.Itoa(i)+"] - expected parser errors")
+	}
+}
+
 func TestIdentifier(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -99,6 +207,16 @@ func TestIdentifier(t *testing.T) {
 				"foobar",
 			},
 		},
+		{
+			input: `as;`,
+			expected: struct {
+				literal string
+				value   string
+			}{
+				"as",
+				"as",
+			},
+		},
 	}
 
 	for i, test := range tests {
@@ -456,6 +574,18 @@ func TestBoolean(t *testing.T) {
 	}
 }
 
+func TestNull(t *testing.T) {
+	p := New(`null;`)
+	program := p.ParseProgram()
+
+	checkParserErrors(t, p)
+	testProgram(t, 0, program)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	assertions.AssertTypeOf(t, reflect.TypeOf(ast.Null{}), stmt.Expression, "stmt.Expression type wrong")
+	assertions.AssertStringEquals(t, "null", stmt.Expression.TokenLexeme(), "stmt.Expression.TokenLexeme() wrong")
+}
+
 func TestIfExpression(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -967,6 +1097,10 @@ func testStatement(stmt ast.Statement) func(*testing.T, int, ast.Statement, ...a
 	switch stmt.(type) {
 	case *ast.LetDeclaration:
 		return testLetDeclaration
+	case *ast.ImportStatement:
+		return testImportStatement
+	case *ast.ExportDeclaration:
+		return testExportDeclaration
 	case *ast.ReturnStatement:
 		return testReturnStatement
 	case *ast.ExpressionStatement:
@@ -1023,6 +1157,22 @@ func testLetDeclaration(t *testing.T, i int, stmt ast.Statement, expected ...any
 	testExpression(stmt.(*ast.LetDeclaration).Value)(t, i, stmt.(*ast.LetDeclaration).Value, expected[1:]...)
 }
 
+func testImportStatement(t *testing.T, i int, stmt ast.Statement, expected ...any) {
+	assertions.AssertTypeOf(t, reflect.TypeOf(ast.ImportStatement{}), stmt, "test["+strconv.Itoa(i)+"] - ast.Statement unexpected type")
+	assertions.AssertStringEquals(t, "import", stmt.TokenLexeme(), "test["+strconv.Itoa(i)+"] - ast.Statement.TokenLexeme() wrong")
+	if 2 != len(expected) {
+		t.Fatalf("testImportStatement: len(expect) wrong: expect=2, actual=%d", len(expected))
+	}
+	testStringLiteral(t, i, stmt.(*ast.ImportStatement).Path, expected[0])
+	testIdentifier(t, i, stmt.(*ast.ImportStatement).Name, expected[1])
+}
+
+func testExportDeclaration(t *testing.T, i int, stmt ast.Statement, expected ...any) {
+	assertions.AssertTypeOf(t, reflect.TypeOf(ast.ExportDeclaration{}), stmt, "test["+strconv.Itoa(i)+"] - ast.Statement unexpected type")
+	assertions.AssertStringEquals(t, "export", stmt.TokenLexeme(), "test["+strconv.Itoa(i)+"] - ast.Statement.TokenLexeme() wrong")
+	testLetDeclaration(t, i, stmt.(*ast.ExportDeclaration).Declaration, expected...)
+}
+
 func testReturnStatement(t *testing.T, i int, stmt ast.Statement, expected ...any) {
 	assertions.AssertTypeOf(t, reflect.TypeOf(ast.ReturnStatement{}), stmt, "test["+strconv.Itoa(i)+"] - ast.Statement unexpected type")
 	assertions.AssertStringEquals(t, "return", stmt.TokenLexeme(), "test["+strconv.Itoa(i)+"] - ast.Statement.TokenLexeme() wrong")
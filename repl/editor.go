@@ -0,0 +1,385 @@
+package repl
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode"
+)
+
+/*****************************************************************************
+ *                                  TYPES                                    *
+ *****************************************************************************/
+
+const HISTORY_SIZE = 1000 // the lines kept in the history
+const INDENT = "    "     // inserted by Tab when there is no word to complete
+
+// ErrInterrupt is returned by Editor.ReadLine when Ctrl-C is typed.
+var ErrInterrupt = errors.New("interrupted")
+
+// Editor reads lines from a terminal in raw mode. A line is edited with the
+// arrow keys and the usual Emacs bindings, recalled from a history that may
+// be kept in a file, searched for backwards with Ctrl-R and completed with
+// Tab.
+type Editor struct {
+	in  *bufio.Reader
+	out io.Writer
+
+	History  []string                     // the lines read, oldest first
+	Complete func(prefix string) []string // the words starting with prefix, if set
+
+	path string // the file every line read is saved in, if any
+}
+
+// edit is the state of the line being read.
+type edit struct {
+	prompt string
+	buf    []rune
+	pos    int
+	index  int    // the line of History shown, or len(History) for buf
+	draft  []rune // the line typed before moving through History
+}
+
+/*****************************************************************************
+ *                              PUBLIC FUNCTIONS                             *
+ *****************************************************************************/
+
+func NewEditor(in io.Reader, out io.Writer) *Editor {
+	return &Editor{in: bufio.NewReader(in), out: out}
+}
+
+// LoadHistory reads the history saved in the file at path, which need not
+// exist, and saves every line read afterwards to it.
+func (e *Editor) LoadHistory(path string) error {
+	e.path = path
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	if len(data) == 0 {
+		lines = nil
+	}
+	if len(lines) > HISTORY_SIZE {
+		lines = lines[len(lines)-HISTORY_SIZE:]
+		if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0600); err != nil {
+			return err
+		}
+	}
+	e.History = append(lines, e.History...)
+	return nil
+}
+
+// ReadLine prints prompt and returns the line typed after it. It returns
+// io.EOF when the input ends or Ctrl-D is typed on an empty line, and
+// ErrInterrupt when Ctrl-C is typed.
+func (e *Editor) ReadLine(prompt string) (string, error) {
+	ed := &edit{prompt: prompt, index: len(e.History)}
+	e.refresh(ed)
+
+	for {
+		r, _, err := e.in.ReadRune()
+		if err != nil {
+			return "", err
+		}
+		if r == ctrl('R') {
+			if r, err = e.search(ed); err != nil {
+				return "", err
+			}
+		}
+
+		switch r {
+		case 0:
+		case '\r', '\n':
+			e.write("\r\n")
+			line := string(ed.buf)
+			e.remember(line)
+			return line, nil
+		case ctrl('C'):
+			e.write("^C\r\n")
+			return "", ErrInterrupt
+		case ctrl('D'):
+			if len(ed.buf) == 0 {
+				e.write("\r\n")
+				return "", io.EOF
+			}
+			ed.delete(ed.pos, ed.pos+1)
+		case ctrl('A'):
+			ed.pos = 0
+		case ctrl('E'):
+			ed.pos = len(ed.buf)
+		case ctrl('B'):
+			ed.pos = max(ed.pos-1, 0)
+		case ctrl('F'):
+			ed.pos = min(ed.pos+1, len(ed.buf))
+		case ctrl('H'), 127:
+			ed.delete(ed.pos-1, ed.pos)
+		case ctrl('K'):
+			ed.delete(ed.pos, len(ed.buf))
+		case ctrl('U'):
+			ed.delete(0, ed.pos)
+		case ctrl('W'):
+			start := ed.pos
+			for start > 0 && unicode.IsSpace(ed.buf[start-1]) {
+				start--
+			}
+			for start > 0 && !unicode.IsSpace(ed.buf[start-1]) {
+				start--
+			}
+			ed.delete(start, ed.pos)
+		case ctrl('L'):
+			e.write("\x1b[H\x1b[2J")
+		case ctrl('P'):
+			e.recall(ed, ed.index-1)
+		case ctrl('N'):
+			e.recall(ed, ed.index+1)
+		case '\t':
+			e.complete(ed)
+		case '\x1b':
+			if err := e.escape(ed); err != nil {
+				return "", err
+			}
+		default:
+			if unicode.IsPrint(r) {
+				ed.insert([]rune{r})
+			}
+		}
+		e.refresh(ed)
+	}
+}
+
+/*****************************************************************************
+ *                             PRIVATE FUNCTIONS                             *
+ *****************************************************************************/
+
+func ctrl(key byte) rune {
+	return rune(key & 0x1f)
+}
+
+func (e *Editor) write(s string) {
+	_, _ = io.WriteString(e.out, s)
+}
+
+// refresh redraws the line and puts the cursor back where it is in the line.
+func (e *Editor) refresh(ed *edit) {
+	e.write("\r" + ed.prompt + string(ed.buf) + "\x1b[K")
+	if back := len(ed.buf) - ed.pos; back > 0 {
+		e.write(fmt.Sprintf("\x1b[%dD", back))
+	}
+}
+
+// remember adds line to the history, unless it is empty or repeats the last
+// line, and saves it to the history file.
+func (e *Editor) remember(line string) {
+	if strings.TrimSpace(line) == "" || (len(e.History) > 0 && e.History[len(e.History)-1] == line) {
+		return
+	}
+	e.History = append(e.History, line)
+	if len(e.History) > HISTORY_SIZE {
+		e.History = e.History[len(e.History)-HISTORY_SIZE:]
+	}
+
+	if e.path == "" {
+		return
+	}
+	file, err := os.OpenFile(e.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+	_, _ = fmt.Fprintln(file, line)
+}
+
+// recall shows the line of the history at index, or the line being typed when
+// index is past the end of the history.
+func (e *Editor) recall(ed *edit, index int) {
+	if index < 0 || index > len(e.History) || index == ed.index {
+		return
+	}
+	if ed.index == len(e.History) {
+		ed.draft = ed.buf
+	}
+
+	ed.index = index
+	if index == len(e.History) {
+		ed.buf = ed.draft
+	} else {
+		ed.buf = []rune(e.History[index])
+	}
+	ed.pos = len(ed.buf)
+}
+
+// escape handles the rest of an escape sequence sent by a special key.
+func (e *Editor) escape(ed *edit) error {
+	r, _, err := e.in.ReadRune()
+	if err != nil {
+		return err
+	}
+	if r != '[' && r != 'O' {
+		return nil
+	}
+
+	// read the parameters up to the final byte of the sequence
+	var params strings.Builder
+	for {
+		r, _, err = e.in.ReadRune()
+		if err != nil {
+			return err
+		}
+		if r >= 0x40 && r <= 0x7e {
+			break
+		}
+		params.WriteRune(r)
+	}
+
+	switch {
+	case r == 'A':
+		e.recall(ed, ed.index-1)
+	case r == 'B':
+		e.recall(ed, ed.index+1)
+	case r == 'C':
+		ed.pos = min(ed.pos+1, len(ed.buf))
+	case r == 'D':
+		ed.pos = max(ed.pos-1, 0)
+	case r == 'H', r == '~' && (params.String() == "1" || params.String() == "7"):
+		ed.pos = 0
+	case r == 'F', r == '~' && (params.String() == "4" || params.String() == "8"):
+		ed.pos = len(ed.buf)
+	case r == '~' && params.String() == "3":
+		ed.delete(ed.pos, ed.pos+1)
+	}
+	return nil
+}
+
+// search finds a line of the history containing what is typed after Ctrl-R,
+// the most recent first, with each Ctrl-R finding an older one. It returns
+// the key ending the search, to be handled as typed, with the line found
+// taking the place of the line being typed. Ctrl-G and Ctrl-C cancel the
+// search, which returns 0.
+func (e *Editor) search(ed *edit) (rune, error) {
+	var query []rune
+	match := len(e.History)
+	found := true
+
+	find := func(from int) {
+		for i := min(from, len(e.History)-1); i >= 0; i-- {
+			if strings.Contains(e.History[i], string(query)) {
+				match, found = i, true
+				return
+			}
+		}
+		found = false
+	}
+
+	for {
+		line, label := "", "reverse-i-search"
+		if match < len(e.History) {
+			line = e.History[match]
+		}
+		if !found {
+			label = "failing " + label
+		}
+		e.write(fmt.Sprintf("\r(%s)`%s': %s\x1b[K", label, string(query), line))
+
+		r, _, err := e.in.ReadRune()
+		if err != nil {
+			return 0, err
+		}
+		switch {
+		case r == ctrl('R'):
+			if found && len(query) > 0 {
+				find(match - 1)
+			}
+		case r == ctrl('H') || r == 127:
+			if len(query) > 0 {
+				query = query[:len(query)-1]
+				find(len(e.History) - 1)
+			}
+		case r == ctrl('G') || r == ctrl('C'):
+			return 0, nil
+		case unicode.IsPrint(r):
+			query = append(query, r)
+			find(match)
+		default:
+			if match < len(e.History) {
+				ed.buf = []rune(e.History[match])
+				ed.pos = len(ed.buf)
+				ed.index = len(e.History)
+			}
+			return r, nil
+		}
+	}
+}
+
+// complete completes the word before the cursor as far as all the words
+// starting with it agree, listing them when they do not, or inserts INDENT
+// when there is no word.
+func (e *Editor) complete(ed *edit) {
+	start := ed.pos
+	for start > 0 && isWordRune(ed.buf[start-1]) {
+		start--
+	}
+	if start == ed.pos {
+		ed.insert([]rune(INDENT))
+		return
+	}
+	if e.Complete == nil {
+		return
+	}
+
+	prefix := string(ed.buf[start:ed.pos])
+	words := e.Complete(prefix)
+	if len(words) == 0 {
+		return
+	}
+
+	common := words[0]
+	for _, word := range words[1:] {
+		for !strings.HasPrefix(word, common) {
+			common = common[:len(common)-1]
+		}
+	}
+	if len(common) > len(prefix) {
+		ed.insert([]rune(common[len(prefix):]))
+		return
+	}
+	if len(words) > 1 {
+		e.write("\r\n" + strings.Join(words, "  ") + "\r\n")
+	}
+}
+
+func (ed *edit) insert(runes []rune) {
+	buf := make([]rune, 0, len(ed.buf)+len(runes))
+	buf = append(buf, ed.buf[:ed.pos]...)
+	buf = append(buf, runes...)
+	ed.buf = append(buf, ed.buf[ed.pos:]...)
+	ed.pos += len(runes)
+}
+
+// delete removes the runes from start up to end, within the line.
+func (ed *edit) delete(start int, end int) {
+	start, end = max(start, 0), min(end, len(ed.buf))
+	if start >= end {
+		return
+	}
+	buf := make([]rune, 0, len(ed.buf)-(end-start))
+	buf = append(buf, ed.buf[:start]...)
+	ed.buf = append(buf, ed.buf[end:]...)
+	if ed.pos > end {
+		ed.pos -= end - start
+	} else if ed.pos > start {
+		ed.pos = start
+	}
+}
+
+func isWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
@@ -0,0 +1,125 @@
+package repl
+
+import (
+	"bytes"
+	"github.com/digital-codex/assertions"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestReadLine(t *testing.T) {
+	words := []string{"len", "let", "puts", "rest"}
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let x = 5;\r", "let x = 5;"},
+		{"let x = 5;\n", "let x = 5;"},
+		{"ab\x1b[Dc\r", "acb"},
+		{"ab\x1b[D\x1b[Cc\r", "abc"},
+		{"bc\x01a\x05d\r", "abcd"},
+		{"bc\x1b[Ha\x1b[Fd\r", "abcd"},
+		{"abc\x7f\r", "ab"},
+		{"abc\x02\x02\x04\r", "ac"},
+		{"abc\x02\x02\x1b[3~\r", "ac"},
+		{"abc\x02\x0b\r", "ab"},
+		{"abc\x02\x15\r", "c"},
+		{"let x = 5\x17\x17\r", "let x "},
+		{"\x1b[A\r", "puts(x)"},
+		{"\x1b[A\x1b[A\r", "let x = 5;"},
+		{"\x10\x10\x10\x0e\r", "puts(x)"},
+		{"abc\x1b[A\x1b[B\r", "abc"},
+		{"\x12let\r", "let x = 5;"},
+		{"\x12x\x12\r", "let x = 5;"},
+		{"\x12put\x1b[D\x7f\r", "puts()"},
+		{"abc\x12put\x07\r", "abc"},
+		{"\x12zzz\r", ""},
+		{"pu\t(x)\r", "puts(x)"},
+		{"l\t\r", "le"},
+		{"x = re\t\r", "x = rest"},
+		{"\tx\r", "    x"},
+		{"qq\t\r", "qq"},
+		{"hé\r", "hé"},
+	}
+
+	for i, test := range tests {
+		editor := NewEditor(strings.NewReader(test.input), io.Discard)
+		editor.History = []string{"let x = 5;", "puts(x)"}
+		editor.Complete = func(prefix string) []string {
+			var matches []string
+			for _, word := range words {
+				if strings.HasPrefix(word, prefix) {
+					matches = append(matches, word)
+				}
+			}
+			return matches
+		}
+
+		actual, err := editor.ReadLine(PROMPT)
+		assertions.AssertEquals(t, nil, err, "test["+strconv.Itoa(i)+"] - ReadLine() error wrong")
+		assertions.AssertStringEquals(t, test.expected, actual, "test["+strconv.Itoa(i)+"] - ReadLine() wrong")
+	}
+}
+
+func TestReadLineErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected error
+	}{
+		{"", io.EOF},
+		{"\x04", io.EOF},
+		{"abc\x03", ErrInterrupt},
+		{"abc", io.EOF},
+	}
+
+	for i, test := range tests {
+		editor := NewEditor(strings.NewReader(test.input), io.Discard)
+		_, err := editor.ReadLine(PROMPT)
+		assertions.AssertEquals(t, test.expected, err, "test["+strconv.Itoa(i)+"] - ReadLine() error wrong")
+	}
+}
+
+func TestRefresh(t *testing.T) {
+	var out bytes.Buffer
+	editor := NewEditor(strings.NewReader("ab\x1b[D\r"), &out)
+	_, _ = editor.ReadLine(PROMPT)
+
+	expected := "\r>> \x1b[K\r>> a\x1b[K\r>> ab\x1b[K\r>> ab\x1b[K\x1b[1D\r\n"
+	assertions.AssertStringEquals(t, expected, out.String(), "ReadLine() output wrong")
+}
+
+func TestHistory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), HISTORY_FILE)
+
+	editor := NewEditor(strings.NewReader("let x = 5;\r\rlet x = 5;\rputs(x)\r"), io.Discard)
+	assertions.AssertEquals(t, nil, editor.LoadHistory(path), "LoadHistory() of a missing file wrong")
+	for i := 0; i < 4; i++ {
+		_, _ = editor.ReadLine(PROMPT)
+	}
+	assertions.AssertDeepEquals(t, []string{"let x = 5;", "puts(x)"}, editor.History, "History wrong")
+
+	data, _ := os.ReadFile(path)
+	assertions.AssertStringEquals(t, "let x = 5;\nputs(x)\n", string(data), "history file wrong")
+
+	editor = NewEditor(strings.NewReader("\x1b[A\r"), io.Discard)
+	assertions.AssertEquals(t, nil, editor.LoadHistory(path), "LoadHistory() wrong")
+	line, _ := editor.ReadLine(PROMPT)
+	assertions.AssertStringEquals(t, "puts(x)", line, "line recalled from the history file wrong")
+
+	var lines []string
+	for i := 0; i < HISTORY_SIZE+10; i++ {
+		lines = append(lines, strconv.Itoa(i))
+	}
+	_ = os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0600)
+	editor = NewEditor(strings.NewReader(""), io.Discard)
+	assertions.AssertEquals(t, nil, editor.LoadHistory(path), "LoadHistory() of a long file wrong")
+	assertions.AssertIntEquals(t, HISTORY_SIZE, len(editor.History), "History length wrong")
+	assertions.AssertStringEquals(t, "10", editor.History[0], "oldest line wrong")
+
+	data, _ = os.ReadFile(path)
+	assertions.AssertIntEquals(t, HISTORY_SIZE, strings.Count(string(data), "\n"), "trimmed history file wrong")
+}
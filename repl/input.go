@@ -0,0 +1,55 @@
+package repl
+
+import (
+	"github.com/digital-codex/monkey/lexer"
+	"github.com/digital-codex/monkey/token"
+)
+
+/*****************************************************************************
+ *                             PRIVATE FUNCTIONS                             *
+ *****************************************************************************/
+
+// incomplete reports whether src could become a program by reading more
+// lines, that is it has unclosed brackets or ends in a token that needs
+// something after it. Input with an illegal token or a stray closing bracket
+// is complete, so its errors are reported at once.
+func incomplete(src string) bool {
+	l := lexer.New(src, nil)
+
+	depth := 0
+	last, before := token.Token{Type: token.EOF}, token.Token{Type: token.EOF}
+	for tok := l.Next(); tok.Type != token.EOF; tok = l.Next() {
+		switch tok.Type {
+		case token.ILLEGAL:
+			return false
+		case token.LPAREN, token.LBRACE, token.LBRACKET:
+			depth++
+		case token.RPAREN, token.RBRACE, token.RBRACKET:
+			depth--
+			if depth < 0 {
+				return false
+			}
+		}
+		last, before = tok, last
+	}
+
+	if depth > 0 {
+		return true
+	}
+
+	// as is only a keyword after the path of an import
+	if last.Type == token.IDENT && last.Lexeme == "as" && before.Type == token.STRING {
+		return true
+	}
+
+	switch last.Type {
+	case token.EQUAL, token.EQUAL_EQUAL, token.BANG, token.BANG_EQUAL,
+		token.PLUS, token.MINUS, token.STAR, token.SLASH, token.LESS, token.MORE,
+		token.COMMA, token.DOT, token.COLON,
+		token.FN, token.IF, token.LET, token.ELSE, token.MACRO,
+		token.EXPORT, token.IMPORT, token.RETURN:
+		return true
+	default:
+		return false
+	}
+}
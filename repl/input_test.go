@@ -0,0 +1,42 @@
+package repl
+
+import (
+	"github.com/digital-codex/assertions"
+	"strconv"
+	"testing"
+)
+
+func TestIncomplete(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{"", false},
+		{"let x = 5;", false},
+		{"1 + 2", false},
+		{"let add = fn(a, b) {", true},
+		{"let add = fn(a, b) {\n    a + b\n}", false},
+		{"puts(1,", true},
+		{"[1, 2", true},
+		{"{\"a\": 1", true},
+		{"1 +", true},
+		{"let x =", true},
+		{"let x = 1 ==", true},
+		{"if (x) { 1 } else", true},
+		{"return", true},
+		{"import \"m.mk\" as", true},
+		{"let as = 1; as", false},
+		{"1 + 2 // a comment (", false},
+		{"1 +\n// a comment\n2", false},
+		{"1)", false},
+		{"fn(x) { x }) (", false},
+		{"\"unterminated", false},
+		{"puts(\"unterminated", false},
+		{"let x = 1 @ {", false},
+	}
+
+	for i, test := range tests {
+		actual := incomplete(test.input)
+		assertions.AssertBoolEquals(t, test.expected, actual, "test["+strconv.Itoa(i)+"] - incomplete() wrong")
+	}
+}
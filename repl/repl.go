@@ -2,13 +2,18 @@ package repl
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
-	"github.com/digital-codex/monkey/evaluator"
+	"github.com/digital-codex/monkey"
+	"github.com/digital-codex/monkey/ast"
 	"github.com/digital-codex/monkey/object"
-	"github.com/digital-codex/monkey/parser"
 	"io"
 	"log"
+	"os"
 	"os/user"
+	"path/filepath"
+	"sort"
+	"strings"
 )
 
 const MONKEY = `
@@ -25,8 +30,16 @@ const MONKEY = `
            '-----'
 `
 const PROMPT = ">> "
+const CONTINUATION = ".. "
+const HISTORY_FILE = ".monkey_history"
 
-func Start(in io.Reader, out io.Writer, current *user.User) {
+// Start reads programs from in and runs them with interpreter, whose standard
+// output is redirected to out. A program spans several lines while it is
+// incomplete, each read after the CONTINUATION prompt, until it is complete or
+// an empty line is read. Lines are read with an Editor when in is a terminal.
+// Each program is resolved before it runs, so a function can only refer to
+// the globals defined by its own program or by earlier ones.
+func Start(in io.Reader, out io.Writer, interpreter *monkey.Interpreter, current *user.User) {
 	_, err := io.WriteString(out, MONKEY)
 	if err != nil {
 		log.Fatal(err)
@@ -35,41 +48,174 @@ func Start(in io.Reader, out io.Writer, current *user.User) {
 	if err != nil {
 		log.Fatal(err)
 	}
-	scanner := bufio.NewScanner(in)
-	env := object.NewEnvironment()
-	macroEnv := object.NewEnvironment()
+	readLine := newReader(in, out, interpreter, current)
+	interpreter.SetStdout(out)
 
+	pending := ""
 	for {
-		_, err := fmt.Fprintf(out, PROMPT)
+		prompt := PROMPT
+		if pending != "" {
+			prompt = CONTINUATION
+		}
+		line, err := readLine(prompt)
+		if errors.Is(err, ErrInterrupt) {
+			pending = ""
+			continue
+		}
+		if err != nil {
+			if pending != "" {
+				run(out, interpreter, pending)
+			}
+			return
+		}
+
+		switch {
+		case pending == "" && strings.HasPrefix(line, ":"):
+			runCommand(out, interpreter, line)
+		case pending != "" && strings.TrimSpace(line) == "":
+			// an empty line runs the program as it is, to report its errors
+			run(out, interpreter, pending)
+			pending = ""
+		default:
+			src := line
+			if pending != "" {
+				src = pending + "\n" + line
+			}
+			if incomplete(src) {
+				pending = src
+				continue
+			}
+			pending = ""
+			run(out, interpreter, src)
+		}
+	}
+}
+
+// newReader returns a function printing a prompt and reading the line typed
+// after it. The line is read with an Editor when in is a terminal, keeping
+// its history in HISTORY_FILE in the home directory of current, and from in
+// as it is otherwise.
+func newReader(in io.Reader, out io.Writer, interpreter *monkey.Interpreter, current *user.User) func(string) (string, error) {
+	if file, ok := in.(*os.File); ok && isTerminal(file.Fd()) {
+		editor := NewEditor(in, out)
+		editor.Complete = completer(interpreter)
+		if current.HomeDir != "" {
+			_ = editor.LoadHistory(filepath.Join(current.HomeDir, HISTORY_FILE))
+		}
+
+		return func(prompt string) (string, error) {
+			restore, err := makeRaw(file.Fd())
+			if err != nil {
+				return "", err
+			}
+			defer restore()
+			return editor.ReadLine(prompt)
+		}
+	}
+
+	scanner := bufio.NewScanner(in)
+	return func(prompt string) (string, error) {
+		_, err := io.WriteString(out, prompt)
 		if err != nil {
 			log.Fatal(err)
 		}
-		scanned := scanner.Scan()
-		if !scanned {
-			return
+		if !scanner.Scan() {
+			return "", io.EOF
 		}
+		return scanner.Text(), nil
+	}
+}
 
-		line := scanner.Text()
-		p := parser.New(line)
-		program := p.ParseProgram()
-		if len(p.Errors()) != 0 {
-			printParseErrors(out, p.Errors())
-			continue
+// completer returns the names bound by interpreter and its builtins starting
+// with a prefix.
+func completer(interpreter *monkey.Interpreter) func(string) []string {
+	return func(prefix string) []string {
+		names := interpreter.Names()
+		if builtins := interpreter.Builtins(); builtins != nil {
+			names = append(names, builtins.Names()...)
+		}
+		sort.Strings(names)
+
+		var words []string
+		for i, name := range names {
+			if strings.HasPrefix(name, prefix) && (i == 0 || name != names[i-1]) {
+				words = append(words, name)
+			}
+		}
+		return words
+	}
+}
+
+// run runs src with interpreter and prints its value or errors.
+func run(out io.Writer, interpreter *monkey.Interpreter, src string) {
+	evaluated, err := interpreter.Run(src)
+	if err != nil {
+		printError(out, err)
+		return
+	}
+	if evaluated != nil {
+		_, err := fmt.Fprintf(out, "%s\n", evaluated.Inspect())
+		if err != nil {
+			log.Fatal(err)
 		}
-		evaluator.DefineMacros(program, macroEnv)
-		expanded := evaluator.ExpandMacros(program, macroEnv)
+	}
+}
 
-		evaluated := evaluator.Eval(expanded, env)
-		if evaluated != nil {
-			_, err := fmt.Fprintf(out, "%s\n", evaluated.Inspect())
+// runCommand runs a line starting with a colon:
+//
+//	:expand <code>   prints code with its macro calls expanded
+//	:expand1 <code>  prints code with one level of macro calls expanded
+func runCommand(out io.Writer, interpreter *monkey.Interpreter, line string) {
+	name, code, _ := strings.Cut(line, " ")
+	switch name {
+	case ":expand", ":expand1":
+		depth := 0
+		if name == ":expand1" {
+			depth = 1
+		}
+		program, err := interpreter.Expand(code, depth)
+		if err != nil {
+			printError(out, err)
+			return
+		}
+		// symbols are renamed so that the expansion can be run as it is
+		program = ast.RenameSymbols(program).(*ast.Program)
+		for _, stmt := range program.Statements {
+			_, err := fmt.Fprintf(out, "%s\n", stmt.String())
 			if err != nil {
 				log.Fatal(err)
 			}
 		}
+	default:
+		_, err := fmt.Fprintf(out, "unknown command %s\n", name)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+}
+
+func printError(out io.Writer, err error) {
+	if parseErr, ok := err.(*monkey.ParseError); ok {
+		printErrors(out, "parser errors:\n", parseErr.Errors)
+		return
+	}
+	if macroErr, ok := err.(*monkey.MacroError); ok {
+		printErrors(out, "macro errors:\n", macroErr.Errors)
+		return
+	}
+	if resolveErr, ok := err.(*monkey.ResolveError); ok {
+		printErrors(out, "resolver errors:\n", resolveErr.Errors)
+		return
+	}
+
+	evaluated := &object.Error{Message: err.Error()}
+	_, err = fmt.Fprintf(out, "%s\n", evaluated.Inspect())
+	if err != nil {
+		log.Fatal(err)
 	}
 }
 
-func printParseErrors(out io.Writer, errors []error) {
+func printErrors(out io.Writer, heading string, errors []error) {
 	_, err := io.WriteString(out, MONKEY)
 	if err != nil {
 		log.Fatal(err)
@@ -78,7 +224,7 @@ func printParseErrors(out io.Writer, errors []error) {
 	if err != nil {
 		log.Fatal(err)
 	}
-	_, err = io.WriteString(out, "parser errors:\n")
+	_, err = io.WriteString(out, heading)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -0,0 +1,51 @@
+package repl
+
+import (
+	"bytes"
+	"github.com/digital-codex/assertions"
+	"github.com/digital-codex/monkey"
+	"os/user"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestStart(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"1 + 2\n", ">> 3.000000\n>> "},
+		{"let add = fn(a, b) {\n    a + b\n};\nadd(1, 2)\n", ">> .. .. >> 3.000000\n>> "},
+		{"[1,\n2]\n", ">> .. [1.000000, 2.000000]\n>> "},
+		{"1 +\n\n2\n", ">> .. Whoops! We ran into some monkey business here!\nparser errors:\n\tError:1:2: expect expression got \"\"\n\n>> 2.000000\n>> "},
+		{":expand1 1\n", ">> 1\n>> "},
+		{"let f = fn() { g() };\nlet g = fn() { 1 };\n", ">> Whoops! We ran into some monkey business here!\nresolver errors:\n\tError:1:15: undefined variable \"g\"\n>> >> "},
+	}
+
+	for i, test := range tests {
+		var out bytes.Buffer
+		Start(strings.NewReader(test.input), &out, monkey.New(nil), &user.User{Username: "test"})
+
+		actual := strings.SplitN(out.String(), "Monkey programming language!\n", 2)[1]
+		actual = strings.ReplaceAll(actual, MONKEY, "")
+		assertions.AssertStringEquals(t, test.expected, actual, "test["+strconv.Itoa(i)+"] - Start() wrong")
+	}
+}
+
+func TestExpandParses(t *testing.T) {
+	input := `let m = macro(a) { let t = gensym("t"); quote(fn(t) { t + unquote(a) }(1)) };
+:expand m(2)
+`
+	var out bytes.Buffer
+	Start(strings.NewReader(input), &out, monkey.New(nil), &user.User{Username: "test"})
+
+	lines := strings.Split(out.String(), "\n")
+	expanded := strings.TrimPrefix(lines[len(lines)-2], PROMPT+PROMPT)
+
+	evaluated, err := monkey.New(nil).Run(expanded)
+	if err != nil {
+		t.Fatalf("expansion %q does not run: %s", expanded, err)
+	}
+	assertions.AssertStringEquals(t, "3.000000", evaluated.Inspect(), "evaluated.Inspect() wrong")
+}
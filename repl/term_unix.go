@@ -0,0 +1,46 @@
+//go:build linux || darwin || dragonfly || freebsd || netbsd || openbsd
+
+package repl
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+/*****************************************************************************
+ *                             PRIVATE FUNCTIONS                             *
+ *****************************************************************************/
+
+// isTerminal reports whether fd is a terminal.
+func isTerminal(fd uintptr) bool {
+	var state syscall.Termios
+	return ioctl(fd, getTermios, &state) == nil
+}
+
+// makeRaw makes the terminal fd pass every key through as it is typed, with
+// no echo and no signals, and returns a function restoring its state.
+func makeRaw(fd uintptr) (func(), error) {
+	var state syscall.Termios
+	if err := ioctl(fd, getTermios, &state); err != nil {
+		return nil, err
+	}
+
+	raw := state
+	raw.Iflag &^= syscall.BRKINT | syscall.ICRNL | syscall.INPCK | syscall.ISTRIP | syscall.IXON
+	raw.Lflag &^= syscall.ECHO | syscall.ICANON | syscall.IEXTEN | syscall.ISIG
+	raw.Cc[syscall.VMIN] = 1
+	raw.Cc[syscall.VTIME] = 0
+	if err := ioctl(fd, setTermios, &raw); err != nil {
+		return nil, err
+	}
+
+	return func() { _ = ioctl(fd, setTermios, &state) }, nil
+}
+
+func ioctl(fd uintptr, request uintptr, state *syscall.Termios) error {
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, request, uintptr(unsafe.Pointer(state)))
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
@@ -0,0 +1,238 @@
+package resolver
+
+import (
+	"fmt"
+	"github.com/digital-codex/monkey/ast"
+	"github.com/digital-codex/monkey/token"
+)
+
+/*****************************************************************************
+ *                                  TYPES                                    *
+ *****************************************************************************/
+
+// Resolver computes the lexical address of every identifier of a program, so
+// the evaluator can find local variables by slot instead of by name.
+type Resolver struct {
+	defined func(name string) bool
+
+	globals  map[string]bool
+	scopes   []*scope
+	bindings map[*ast.Identifier]binding
+
+	errors []error
+}
+
+// scope is the scope of a function: its parameters followed by the names it
+// declares with let, anywhere in its body outside nested functions.
+type scope struct {
+	names []string
+}
+
+type binding struct {
+	scope ast.Scope
+	depth int
+	slot  int
+}
+
+/*****************************************************************************
+ *                              PUBLIC FUNCTIONS                             *
+ *****************************************************************************/
+
+// New returns a Resolver that accepts references to the top-level names of
+// the program and to the names for which defined reports true, such as the
+// globals of an earlier run and the builtins. defined may be nil.
+func New(defined func(name string) bool) *Resolver {
+	return &Resolver{
+		defined:  defined,
+		globals:  make(map[string]bool),
+		bindings: make(map[*ast.Identifier]binding),
+		errors:   []error{},
+	}
+}
+
+func (r *Resolver) Errors() []error {
+	return r.errors
+}
+
+// Resolve sets the Scope, Depth and Slot of the identifiers of program and the
+// Locals of its function literals. References to undefined variables are
+// recorded as errors. Macro literals and quoted code outside of unquote calls
+// are left unresolved.
+func (r *Resolver) Resolve(program *ast.Program) {
+	for _, stmt := range program.Statements {
+		r.hoist(stmt)
+	}
+	for _, stmt := range program.Statements {
+		r.resolve(stmt)
+	}
+}
+
+/*****************************************************************************
+ *                             PRIVATE FUNCTIONS                             *
+ *****************************************************************************/
+
+// hoist declares the names bound by node in the current scope, so functions
+// can refer to names declared after them.
+func (r *Resolver) hoist(node ast.Node) {
+	switch node := node.(type) {
+	case *ast.LetDeclaration:
+		r.declare(node.Name.Value)
+		r.hoist(node.Value)
+	case *ast.ImportStatement:
+		r.declare(node.Name.Value)
+	case *ast.FunctionLiteral, *ast.MacroLiteral:
+		// these open scopes of their own
+	case *ast.CallExpression:
+		if isQuoteCall(node) {
+			for _, unquoted := range unquotedArguments(node) {
+				r.hoist(unquoted)
+			}
+			return
+		}
+		for _, child := range ast.Children(node) {
+			r.hoist(child)
+		}
+	default:
+		for _, child := range ast.Children(node) {
+			r.hoist(child)
+		}
+	}
+}
+
+func (r *Resolver) resolve(node ast.Node) {
+	switch node := node.(type) {
+	case *ast.Identifier:
+		r.resolveIdentifier(node)
+	case *ast.LetDeclaration:
+		r.resolveDeclaration(node.Name)
+		r.resolve(node.Value)
+	case *ast.ImportStatement:
+		r.resolveDeclaration(node.Name)
+	case *ast.FunctionLiteral:
+		r.resolveFunctionLiteral(node)
+	case *ast.MacroLiteral:
+		// macro bodies run while expanding, before the program is resolved
+	case *ast.CallExpression:
+		if isQuoteCall(node) {
+			for _, unquoted := range unquotedArguments(node) {
+				r.resolve(unquoted)
+			}
+			return
+		}
+		for _, child := range ast.Children(node) {
+			r.resolve(child)
+		}
+	default:
+		for _, child := range ast.Children(node) {
+			r.resolve(child)
+		}
+	}
+}
+
+func (r *Resolver) resolveFunctionLiteral(node *ast.FunctionLiteral) {
+	s := &scope{}
+	for _, param := range node.Parameters {
+		s.names = append(s.names, param.Value)
+	}
+
+	r.scopes = append(r.scopes, s)
+	defer func() { r.scopes = r.scopes[:len(r.scopes)-1] }()
+
+	r.hoist(node.Body)
+	for i, param := range node.Parameters {
+		r.bind(param, binding{scope: ast.LOCAL, slot: i})
+	}
+	r.resolve(node.Body)
+
+	node.Locals = s.names
+}
+
+func (r *Resolver) resolveDeclaration(name *ast.Identifier) {
+	if len(r.scopes) == 0 {
+		r.bind(name, binding{scope: ast.GLOBAL})
+		return
+	}
+	r.bind(name, binding{scope: ast.LOCAL, slot: r.scopes[len(r.scopes)-1].lookup(name.Value)})
+}
+
+func (r *Resolver) resolveIdentifier(node *ast.Identifier) {
+	for depth := 0; depth < len(r.scopes); depth++ {
+		if slot := r.scopes[len(r.scopes)-1-depth].lookup(node.Value); slot >= 0 {
+			r.bind(node, binding{scope: ast.LOCAL, depth: depth, slot: slot})
+			return
+		}
+	}
+
+	if r.globals[node.Value] || (r.defined != nil && r.defined(node.Value)) {
+		r.bind(node, binding{scope: ast.GLOBAL})
+		return
+	}
+
+	r.error(node.Token, fmt.Sprintf("undefined variable %q", node.Value))
+}
+
+func (r *Resolver) declare(name string) {
+	if len(r.scopes) == 0 {
+		r.globals[name] = true
+		return
+	}
+
+	s := r.scopes[len(r.scopes)-1]
+	if s.lookup(name) < 0 {
+		s.names = append(s.names, name)
+	}
+}
+
+// bind records the address of ident. Macros may splice one node into several
+// places; when these disagree ident is left to be looked up by name.
+func (r *Resolver) bind(ident *ast.Identifier, b binding) {
+	if prev, ok := r.bindings[ident]; ok && prev != b {
+		b = binding{scope: ast.UNRESOLVED}
+	}
+	r.bindings[ident] = b
+
+	ident.Scope = b.scope
+	ident.Depth = b.depth
+	ident.Slot = b.slot
+}
+
+func (r *Resolver) error(t token.Token, msg string) {
+	r.errors = append(r.errors, fmt.Errorf("Error:%d:%d: %s", t.Line, t.Start, msg))
+}
+
+// lookup returns the slot of name, the last one when parameters repeat it, or
+// -1.
+func (s *scope) lookup(name string) int {
+	for i := len(s.names) - 1; i >= 0; i-- {
+		if s.names[i] == name {
+			return i
+		}
+	}
+	return -1
+}
+
+func isQuoteCall(node *ast.CallExpression) bool {
+	return node.Function.TokenLexeme() == "quote"
+}
+
+func isUnquoteCall(node ast.Node) bool {
+	ce, ok := node.(*ast.CallExpression)
+	if !ok || len(ce.Argument) != 1 {
+		return false
+	}
+	return ce.Function.TokenLexeme() == "unquote" || ce.Function.TokenLexeme() == "unquote_splice"
+}
+
+// unquotedArguments returns the arguments of the unquote and unquote_splice
+// calls within a call to quote, which are evaluated when the quote is.
+func unquotedArguments(node ast.Node) []ast.Node {
+	var unquoted []ast.Node
+	for _, child := range ast.Children(node) {
+		if isUnquoteCall(child) {
+			unquoted = append(unquoted, child.(*ast.CallExpression).Argument[0])
+			continue
+		}
+		unquoted = append(unquoted, unquotedArguments(child)...)
+	}
+	return unquoted
+}
@@ -0,0 +1,138 @@
+package resolver
+
+import (
+	"fmt"
+	"github.com/digital-codex/assertions"
+	"github.com/digital-codex/monkey/ast"
+	"github.com/digital-codex/monkey/parser"
+	"strconv"
+	"testing"
+)
+
+func TestResolve(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{`let x = 5; x;`, []string{"x global", "x global"}},
+		{`let f = fn(a, b) { a + b };`, []string{"f global", "a local 0 0", "b local 0 1", "a local 0 0", "b local 0 1"}},
+		{`let f = fn(a) { let b = a; b };`, []string{"f global", "a local 0 0", "b local 0 1", "a local 0 0", "b local 0 1"}},
+		{`let f = fn(a) { fn(b) { a + b } };`, []string{"f global", "a local 0 0", "b local 0 0", "a local 1 0", "b local 0 0"}},
+		{`let f = fn() { g() }; let g = fn() { 1 };`, []string{"f global", "g global", "g global"}},
+		{`let f = fn() { let h = fn() { k }; let k = 1; h() };`, []string{"f global", "h local 0 0", "k local 1 1", "k local 0 1", "h local 0 0"}},
+		{`let f = fn(a) { if (a) { let b = 1; } b };`, []string{"f global", "a local 0 0", "a local 0 0", "b local 0 1", "b local 0 1"}},
+		{`let f = fn(x, x) { x };`, []string{"f global", "x local 0 0", "x local 0 1", "x local 0 1"}},
+		{`len("abc");`, []string{"len global"}},
+		{`let f = fn(a) { quote(a + unquote(a)) };`, []string{"f global", "a local 0 0", "quote unresolved", "a unresolved", "unquote unresolved", "a local 0 0"}},
+	}
+
+	for i, test := range tests {
+		program := resolve(t, test.input, func(name string) bool { return name == "len" })
+		assertions.AssertDeepEquals(t, test.expected, addresses(program), "test["+strconv.Itoa(i)+"] - addresses wrong")
+	}
+}
+
+func TestLocals(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{`fn() { 1 }`, nil},
+		{`fn(a, b) { a }`, []string{"a", "b"}},
+		{`fn(a) { let b = 1; if (a) { let c = 2; let b = 3; } fn(d) { let e = d; e } }`, []string{"a", "b", "c"}},
+		{`fn(a) { let a = 1; a }`, []string{"a"}},
+	}
+
+	for i, test := range tests {
+		program := resolve(t, test.input, nil)
+		fn := program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.FunctionLiteral)
+		assertions.AssertDeepEquals(t, test.expected, fn.Locals, "test["+strconv.Itoa(i)+"] - fn.Locals wrong")
+	}
+}
+
+func TestUndefinedVariables(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{`x`, []string{`Error:1:0: undefined variable "x"`}},
+		{`let f = fn(a) { a + b };`, []string{`Error:1:20: undefined variable "b"`}},
+		{`let f = fn() { let a = 1; }; a`, []string{`Error:1:29: undefined variable "a"`}},
+		{"let x = 1;\ny + z", []string{`Error:2:11: undefined variable "y"`, `Error:2:15: undefined variable "z"`}},
+		{`unquote(1)`, []string{`Error:1:0: undefined variable "unquote"`}},
+		{`let m = macro(a) { b }; quote(c)`, []string{}},
+	}
+
+	for i, test := range tests {
+		program := parse(t, test.input)
+		r := New(nil)
+		r.Resolve(program)
+
+		var msgs []string
+		for _, err := range r.Errors() {
+			msgs = append(msgs, err.Error())
+		}
+		if len(test.expected) == 0 {
+			assertions.AssertIntEquals(t, 0, len(msgs), "test["+strconv.Itoa(i)+"] - len(r.Errors()) wrong")
+			continue
+		}
+		assertions.AssertDeepEquals(t, test.expected, msgs, "test["+strconv.Itoa(i)+"] - r.Errors() wrong")
+	}
+}
+
+func TestConflictingAddresses(t *testing.T) {
+	program := parse(t, `let f = fn(b) { b }; let g = fn(b) { fn(c) { c } };`)
+
+	// splice the body of f into the inner function of g, as a macro might
+	f := program.Statements[0].(*ast.LetDeclaration).Value.(*ast.FunctionLiteral)
+	g := program.Statements[1].(*ast.LetDeclaration).Value.(*ast.FunctionLiteral)
+	inner := g.Body.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.FunctionLiteral)
+	inner.Body.Statements = append(inner.Body.Statements, f.Body.Statements[0])
+
+	r := New(nil)
+	r.Resolve(program)
+
+	ident := f.Body.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.Identifier)
+	assertions.AssertIntEquals(t, 0, len(r.Errors()), "len(r.Errors()) wrong")
+	assertions.AssertBoolEquals(t, true, ident.Scope == ast.UNRESOLVED, "a node spliced into two scopes should be left unresolved")
+}
+
+func resolve(t *testing.T, input string, defined func(string) bool) *ast.Program {
+	program := parse(t, input)
+
+	r := New(defined)
+	r.Resolve(program)
+	if len(r.Errors()) != 0 {
+		t.Fatalf("resolver has %d errors: %v", len(r.Errors()), r.Errors())
+	}
+
+	return program
+}
+
+func parse(t *testing.T, input string) *ast.Program {
+	p := parser.New(input)
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser has %d errors: %v", len(p.Errors()), p.Errors())
+	}
+	return program
+}
+
+// addresses lists the identifiers of node in source order with their address.
+func addresses(node ast.Node) []string {
+	var out []string
+	if ident, ok := node.(*ast.Identifier); ok {
+		switch ident.Scope {
+		case ast.LOCAL:
+			out = append(out, fmt.Sprintf("%s local %d %d", ident.Value, ident.Depth, ident.Slot))
+		case ast.GLOBAL:
+			out = append(out, ident.Value+" global")
+		default:
+			out = append(out, ident.Value+" unresolved")
+		}
+	}
+	for _, child := range ast.Children(node) {
+		out = append(out, addresses(child)...)
+	}
+	return out
+}
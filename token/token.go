@@ -64,9 +64,17 @@ const (
 	ELSE
 	TRUE
 	FALSE
+	NULL
 	MACRO
+	EXPORT
+	IMPORT
 	RETURN
 
+	/*
+	 * Comments
+	 */
+	COMMENT
+
 	EOF
 )
 
@@ -120,9 +128,17 @@ var tokens = [...]string{
 	ELSE:   "else",
 	TRUE:   "true",
 	FALSE:  "false",
+	NULL:   "null",
 	MACRO:  "macro",
+	EXPORT: "export",
+	IMPORT: "import",
 	RETURN: "return",
 
+	/*
+	 * Comments
+	 */
+	COMMENT: "COMMENT",
+
 	EOF: "",
 }
 